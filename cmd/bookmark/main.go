@@ -5,32 +5,40 @@ import (
 	"log"
 	"os"
 
+	"github.com/abhijith/bookmark-cli/internal/bookmarks"
 	"github.com/abhijith/bookmark-cli/internal/browser"
+	"github.com/abhijith/bookmark-cli/internal/dupes"
+	"github.com/abhijith/bookmark-cli/internal/exporter"
+	"github.com/abhijith/bookmark-cli/internal/fetcher"
 	"github.com/abhijith/bookmark-cli/internal/importer"
-	"github.com/abhijith/bookmark-cli/internal/redis"
+	"github.com/abhijith/bookmark-cli/internal/migrate"
 	"github.com/abhijith/bookmark-cli/internal/searcher"
+	"github.com/abhijith/bookmark-cli/internal/store"
+	"github.com/abhijith/bookmark-cli/internal/tags"
 	"github.com/urfave/cli/v2"
 )
 
-func main() {
-	// Initialize Redis connection
-	redisClient := redis.NewClient()
-	defer redisClient.Close()
-
-	app := &cli.App{
-		Name:  "bc",
-		Usage: "Bookmark CLI - Ultra-fast bookmark manager",
-		Description: `A powerful bookmark manager with Redis backend and interactive search.
+// description is shown by `bc help` and by bc without arguments
+const description = `A powerful bookmark manager with Redis backend and interactive search.
 
 Commands:
 ┌─────────┬─────────────────────────────────────────────────────────────┐
 │ Command │ Description                                                │
 ├─────────┼─────────────────────────────────────────────────────────────┤
+│ add     │ Bookmark a URL, fetching its title and description         │
+│ show    │ Show a bookmark by ID or URL                               │
+│ edit    │ Edit a bookmark in $EDITOR                                 │
+│ rm      │ Remove bookmarks by ID or URL                              │
+│ tags    │ List, rename, merge, delete, add and remove tags           │
+│ tree    │ Show folders and nested tags with counts                   │
 │ import  │ Import bookmarks from JSON file                            │
 │ browser │ Auto-import bookmarks from browsers (Chrome, Firefox, Safari, Zen, Arc)│
 │ sync    │ Sync and deduplicate bookmarks from all browsers          │
-│ search  │ Interactive search with filters and shortcuts             │
+│ export  │ Export bookmarks as HTML, JSON, JSONL, CSV or Markdown     │
+│ search  │ Search from the command line or full-screen               │
 │ clean   │ Remove duplicate bookmarks                                 │
+│ dupes   │ Find near-duplicate bookmarks and merge them               │
+│ migrate │ Upgrade the Redis data layout                              │
 └─────────┴─────────────────────────────────────────────────────────────┘
 
 Search Shortcuts:
@@ -38,34 +46,202 @@ Search Shortcuts:
 │ Shortcut│ Description                                                │
 ├─────────┼─────────────────────────────────────────────────────────────┤
 │ /query  │ Text search in title, description, and URL                 │
-│ #tag    │ Filter by tags (e.g., #database #redis)                   │
+│ #tag    │ Filter by tags; #dev also matches dev/go                  │
 │ @date   │ Filter by date range (e.g., @2023-01-01 @2023-12-31)      │
 │ !llm    │ Enable LLM processing (future feature)                     │
 └─────────┴─────────────────────────────────────────────────────────────┘
 
 Examples:
+  bc add https://go.dev --tag go
   bc import bookmarks.json
   bc browser chrome
   bc sync
   bc search
-  bc clean`,
+  bc tag add go --query "site:go.dev"
+  bc search --json tag:golang
+  bc export html --query "tag:go" > bookmarks.html
+  bc clean
+  bc dupes --auto --threshold 0.9`
+
+func main() {
+	// Initialize the configured storage backend
+	st, err := store.Open()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer st.Close()
+
+	app := &cli.App{
+		Name:        "bc",
+		Usage:       "Bookmark CLI - Ultra-fast bookmark manager",
+		Description: description,
+		Before: func(c *cli.Context) error {
+			// Help and migrate must work against an outdated schema
+			switch c.Args().First() {
+			case "", "help", "h", "migrate":
+				return nil
+			}
+			return migrate.Check(st)
+		},
 		Commands: []*cli.Command{
+			{
+				Name:      "add",
+				Usage:     "Bookmark a URL, reading its title and description from the page",
+				ArgsUsage: "<url>",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "title",
+						Usage: "Title (default: the page title)",
+					},
+					&cli.StringFlag{
+						Name:  "desc",
+						Usage: "Description (default: the page description)",
+					},
+					&cli.StringSliceFlag{
+						Name:  "tag",
+						Usage: "Tag to add; repeat for several",
+					},
+					&cli.BoolFlag{
+						Name:  "no-fetch",
+						Usage: "Do not fetch the page",
+					},
+					&cli.DurationFlag{
+						Name:  "timeout",
+						Value: fetcher.DefaultTimeout,
+						Usage: "Time limit for fetching the page",
+					},
+				},
+				Action: importer.AddCommand(st),
+			},
+			{
+				Name:      "show",
+				Usage:     "Show a bookmark",
+				ArgsUsage: "<id|url>",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "json",
+						Usage: "Print the bookmark as JSON",
+					},
+				},
+				Action: bookmarks.ShowCommand(st),
+			},
+			{
+				Name:      "edit",
+				Usage:     "Edit a bookmark in $EDITOR",
+				ArgsUsage: "<id|url>",
+				Action:    bookmarks.EditCommand(st),
+			},
+			{
+				Name:      "rm",
+				Usage:     "Remove bookmarks",
+				ArgsUsage: "<id|url>...",
+				Action:    bookmarks.RemoveCommand(st),
+			},
+			{
+				Name:    "tags",
+				Aliases: []string{"tag"},
+				Usage:   "List and manage tags",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "list",
+						Usage: "List tags with their bookmark counts",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "sort",
+								Value: "count",
+								Usage: "Order: count or name",
+							},
+						},
+						Action: tags.ListCommand(st),
+					},
+					{
+						Name:      "rename",
+						Usage:     "Rename a tag on every bookmark",
+						ArgsUsage: "<old> <new>",
+						Action:    tags.RenameCommand(st),
+					},
+					{
+						Name:      "merge",
+						Usage:     "Merge tags into the last one given",
+						ArgsUsage: "<tag>... <into>",
+						Action:    tags.MergeCommand(st),
+					},
+					{
+						Name:      "delete",
+						Usage:     "Remove tags from every bookmark",
+						ArgsUsage: "<tag>...",
+						Action:    tags.DeleteCommand(st),
+					},
+					{
+						Name:      "add",
+						Usage:     "Tag bookmarks by ID or URL, or every search result",
+						ArgsUsage: "<tag> [<id|url>...]",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "query",
+								Usage: "Tag every bookmark matching this search",
+							},
+						},
+						Action: tags.AddCommand(st),
+					},
+					{
+						Name:      "remove",
+						Usage:     "Untag bookmarks by ID or URL, or every search result",
+						ArgsUsage: "<tag> [<id|url>...]",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "query",
+								Usage: "Untag every bookmark matching this search",
+							},
+						},
+						Action: tags.RemoveCommand(st),
+					},
+				},
+				Action: tags.ListCommand(st),
+			},
+			{
+				Name:  "tree",
+				Usage: "Show the folder and tag hierarchies with bookmark counts",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "folders",
+						Usage: "Only show folders",
+					},
+					&cli.BoolFlag{
+						Name:  "tags",
+						Usage: "Only show tags",
+					},
+				},
+				Action: tags.TreeCommand(st),
+			},
 			{
 				Name:      "import",
 				Usage:     "Import bookmarks from JSON file",
 				ArgsUsage: "<file>",
-				Action:    importer.ImportCommand(redisClient),
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "Show what would be imported without changing anything",
+					},
+				},
+				Action: importer.ImportCommand(st),
 			},
 			{
 				Name:      "import-html",
 				Usage:     "Import bookmarks from HTML export file",
 				ArgsUsage: "<file>",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "Show what would be imported without changing anything",
+					},
+				},
 				Action: func(c *cli.Context) error {
 					if c.NArg() < 1 {
 						return cli.Exit("Missing HTML file argument", 1)
 					}
-					importer := browser.NewBrowserImporter(redisClient)
-					return importer.ImportFromHTMLFile(c.Args().Get(0))
+					bi := browser.NewBrowserImporter(st, importer.OptionsFromFlags(c))
+					return bi.ImportFromHTMLFile(c.Args().Get(0))
 				},
 			},
 			{
@@ -75,49 +251,64 @@ Examples:
 					{
 						Name:  "chrome",
 						Usage: "Import from Chrome browser",
+						Flags: []cli.Flag{browserDryRunFlag},
 						Action: func(c *cli.Context) error {
-							importer := browser.NewBrowserImporter(redisClient)
-							return importer.ImportFromChrome()
+							bi := browser.NewBrowserImporter(st, importer.OptionsFromFlags(c))
+							return bi.ImportFromChrome()
 						},
 					},
 					{
 						Name:  "firefox",
 						Usage: "Import from Firefox browser",
+						Flags: []cli.Flag{
+							browserDryRunFlag,
+							&cli.StringFlag{
+								Name:  "backup",
+								Usage: "Import a bookmark backup (.json or .jsonlz4) instead, or \"latest\" for the newest",
+							},
+						},
 						Action: func(c *cli.Context) error {
-							importer := browser.NewBrowserImporter(redisClient)
-							return importer.ImportFromFirefox()
+							bi := browser.NewBrowserImporter(st, importer.OptionsFromFlags(c))
+							if c.IsSet("backup") {
+								return bi.ImportFromFirefoxBackup(c.String("backup"))
+							}
+							return bi.ImportFromFirefox()
 						},
 					},
 					{
 						Name:  "safari",
 						Usage: "Import from Safari browser",
+						Flags: []cli.Flag{browserDryRunFlag},
 						Action: func(c *cli.Context) error {
-							importer := browser.NewBrowserImporter(redisClient)
-							return importer.ImportFromSafari()
+							bi := browser.NewBrowserImporter(st, importer.OptionsFromFlags(c))
+							return bi.ImportFromSafari()
 						},
 					},
 					{
 						Name:  "zen",
 						Usage: "Import from Zen browser",
+						Flags: []cli.Flag{browserDryRunFlag},
 						Action: func(c *cli.Context) error {
-							importer := browser.NewBrowserImporter(redisClient)
-							return importer.ImportFromZen()
+							bi := browser.NewBrowserImporter(st, importer.OptionsFromFlags(c))
+							return bi.ImportFromZen()
 						},
 					},
 					{
 						Name:  "arc",
 						Usage: "Import from Arc browser",
+						Flags: []cli.Flag{browserDryRunFlag},
 						Action: func(c *cli.Context) error {
-							importer := browser.NewBrowserImporter(redisClient)
-							return importer.ImportFromArc()
+							bi := browser.NewBrowserImporter(st, importer.OptionsFromFlags(c))
+							return bi.ImportFromArc()
 						},
 					},
 					{
 						Name:  "all",
 						Usage: "Import from all available browsers",
+						Flags: []cli.Flag{browserDryRunFlag},
 						Action: func(c *cli.Context) error {
-							importer := browser.NewBrowserImporter(redisClient)
-							return importer.AutoImport()
+							bi := browser.NewBrowserImporter(st, importer.OptionsFromFlags(c))
+							return bi.AutoImport()
 						},
 					},
 				},
@@ -125,53 +316,129 @@ Examples:
 			{
 				Name:  "sync",
 				Usage: "Sync and deduplicate bookmarks from all browsers",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "Show what would be imported without changing anything",
+					},
+				},
 				Action: func(c *cli.Context) error {
-					importer := browser.NewBrowserImporter(redisClient)
-					return importer.SyncBookmarks()
+					bi := browser.NewBrowserImporter(st, importer.OptionsFromFlags(c))
+					return bi.SyncBookmarks()
 				},
 			},
 			{
-				Name:   "search",
-				Usage:  "Interactive search mode",
-				Action: searcher.SearchCommand(redisClient),
+				Name:  "export",
+				Usage: "Export bookmarks to stdout",
+				Subcommands: []*cli.Command{
+					exportCommand(st, "html", "Export a Netscape bookmark file that browsers and import-html can read", tagFoldersFlag),
+					exportCommand(st, "json", "Export the JSON document that import reads"),
+					exportCommand(st, "jsonl", "Export one JSON bookmark per line"),
+					exportCommand(st, "csv", "Export CSV with the columns of search --csv"),
+					exportCommand(st, "markdown", "Export a Markdown list of links under folder headings", tagFoldersFlag),
+				},
 			},
 			{
-				Name:   "clean",
-				Usage:  "Remove duplicate bookmarks",
-				Action: importer.CleanCommand(redisClient),
+				Name:      "search",
+				Usage:     "Search bookmarks, or browse them full-screen without a query",
+				ArgsUsage: "[query]",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:    "interactive",
+						Aliases: []string{"i"},
+						Usage:   "Use the line-based search prompt instead of the full-screen view",
+					},
+					&cli.BoolFlag{
+						Name:  "json",
+						Usage: "Print results as JSON",
+					},
+					&cli.BoolFlag{
+						Name:  "csv",
+						Usage: "Print results as CSV",
+					},
+					&cli.BoolFlag{
+						Name:  "tsv",
+						Usage: "Print results as tab-separated values",
+					},
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "Print each result with a Go template, e.g. '{{.Title}} {{.URL}}'",
+					},
+					&cli.IntFlag{
+						Name:  "limit",
+						Value: 20,
+						Usage: "Maximum number of results (0 for all)",
+					},
+					&cli.IntFlag{
+						Name:  "offset",
+						Usage: "Number of results to skip",
+					},
+					&cli.StringFlag{
+						Name:  "sort",
+						Value: searcher.SortRelevance,
+						Usage: "Result order: relevance, date or title",
+					},
+				},
+				Action: searcher.SearchCommand(st),
+			},
+			{
+				Name:  "clean",
+				Usage: "Merge duplicate bookmarks and repair the indexes",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "Report problems without fixing them",
+					},
+				},
+				Action: importer.CleanCommand(st),
+			},
+			{
+				Name:  "repair-ids",
+				Usage: "Give every bookmark the ID of its canonical URL",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "Report how many IDs would change without changing them",
+					},
+				},
+				Action: bookmarks.RepairIDsCommand(st),
+			},
+			{
+				Name:  "dupes",
+				Usage: "Find likely duplicate bookmarks and merge them",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "auto",
+						Usage: "Merge every cluster at or above --threshold without asking",
+					},
+					&cli.Float64Flag{
+						Name:  "threshold",
+						Value: 0.9,
+						Usage: "Confidence (0-1) a cluster needs to be merged by --auto",
+					},
+					&cli.Float64Flag{
+						Name:  "min",
+						Value: 0.5,
+						Usage: "Confidence (0-1) a cluster needs to be listed",
+					},
+				},
+				Action: dupes.FindCommand(st),
+			},
+			{
+				Name:  "migrate",
+				Usage: "Upgrade the database to the latest schema version",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "List pending migrations without applying them",
+					},
+				},
+				Action: migrate.MigrateCommand(st),
 			},
 		},
 		Action: func(c *cli.Context) error {
 			if c.NArg() == 0 {
-				fmt.Println(`A powerful bookmark manager with Redis backend and interactive search.
-
-Commands:
-┌─────────┬─────────────────────────────────────────────────────────────┐
-│ Command │ Description                                                │
-├─────────┼─────────────────────────────────────────────────────────────┤
-│ import  │ Import bookmarks from JSON file                            │
-│ browser │ Auto-import bookmarks from browsers (Chrome, Firefox, Safari, Zen, Arc)│
-│ sync    │ Sync and deduplicate bookmarks from all browsers          │
-│ search  │ Interactive search with filters and shortcuts             │
-│ clean   │ Remove duplicate bookmarks                                 │
-└─────────┴─────────────────────────────────────────────────────────────┘
-
-Search Shortcuts:
-┌─────────┬─────────────────────────────────────────────────────────────┐
-│ Shortcut│ Description                                                │
-├─────────┼─────────────────────────────────────────────────────────────┤
-│ /query  │ Text search in title, description, and URL                 │
-│ #tag    │ Filter by tags (e.g., #database #redis)                   │
-│ @date   │ Filter by date range (e.g., @2023-01-01 @2023-12-31)      │
-│ !llm    │ Enable LLM processing (future feature)                     │
-└─────────┴─────────────────────────────────────────────────────────────┘
-
-Examples:
-  bc import bookmarks.json
-  bc browser chrome
-  bc sync
-  bc search
-  bc clean`)
+				fmt.Println(description)
 				return nil
 			}
 			return cli.ShowAppHelp(c)
@@ -182,3 +449,31 @@ Examples:
 		log.Fatal(err)
 	}
 }
+
+// browserDryRunFlag is declared on each browser subcommand so it can follow
+// the browser name
+var browserDryRunFlag = &cli.BoolFlag{
+	Name:  "dry-run",
+	Usage: "Show what would be imported without changing anything",
+}
+
+var tagFoldersFlag = &cli.BoolFlag{
+	Name:  "tag-folders",
+	Usage: "File bookmarks without a folder under their first tag",
+}
+
+// exportCommand is the `export` subcommand writing format
+func exportCommand(st store.Store, format, usage string, flags ...cli.Flag) *cli.Command {
+	return &cli.Command{
+		Name:      format,
+		Usage:     usage,
+		ArgsUsage: "[query]",
+		Flags: append([]cli.Flag{
+			&cli.StringFlag{
+				Name:  "query",
+				Usage: "Only export bookmarks matching this search",
+			},
+		}, flags...),
+		Action: exporter.Command(st, format),
+	}
+}
@@ -0,0 +1,179 @@
+package bookmarks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/abhijith/bookmark-cli/internal/models"
+	"github.com/abhijith/bookmark-cli/internal/store"
+	"github.com/abhijith/bookmark-cli/internal/urlnorm"
+	"github.com/schollz/progressbar/v3"
+	"github.com/urfave/cli/v2"
+)
+
+// Resolve finds a bookmark by ID, short ID or URL
+func Resolve(ctx context.Context, st store.Store, ref string) (models.Bookmark, error) {
+	ids := []string{ref, store.NewID(ref)}
+	// Accept URLs typed without a scheme, like `bm add` does
+	if u, err := urlnorm.Clean(ref); err == nil && strings.Contains(ref, ".") {
+		ids = append(ids, store.NewID(u))
+	}
+	for _, id := range ids {
+		bm, err := st.Get(ctx, id)
+		if err == nil {
+			return bm, nil
+		}
+		if err != store.ErrNotFound {
+			return models.Bookmark{}, err
+		}
+	}
+
+	// A short ID is any unique prefix of a full one
+	if isHex(ref) && len(ref) >= store.MinIDPrefix {
+		matches, err := st.IDsWithPrefix(ctx, strings.ToLower(ref))
+		if err != nil {
+			return models.Bookmark{}, err
+		}
+		switch len(matches) {
+		case 0:
+		case 1:
+			return st.Get(ctx, matches[0])
+		default:
+			return models.Bookmark{}, fmt.Errorf("ID prefix %q is ambiguous: it matches %d bookmarks", ref, len(matches))
+		}
+	}
+
+	// Bookmarks whose ID predates the URL hash can still be found by URL
+	bm, err := store.FindByURL(ctx, st, ref)
+	if err == store.ErrNotFound {
+		return models.Bookmark{}, fmt.Errorf("no bookmark with ID or URL %q", ref)
+	}
+	return bm, err
+}
+
+func isHex(s string) bool {
+	for _, r := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// ShowCommand prints one bookmark
+func ShowCommand(st store.Store) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		if c.NArg() < 1 {
+			return cli.Exit("Missing bookmark ID or URL", 1)
+		}
+
+		bm, err := Resolve(context.Background(), st, c.Args().First())
+		if err != nil {
+			return cli.Exit(err.Error(), 1)
+		}
+
+		if c.Bool("json") {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(bm)
+		}
+		printBookmark(bm)
+		return nil
+	}
+}
+
+// RemoveCommand deletes the bookmarks given by ID or URL. Unknown ones are
+// reported and the rest are still removed.
+func RemoveCommand(st store.Store) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		if c.NArg() < 1 {
+			return cli.Exit("Missing bookmark ID", 1)
+		}
+
+		ctx := context.Background()
+		failed := 0
+		for _, ref := range c.Args().Slice() {
+			bm, err := Resolve(ctx, st, ref)
+			if err == nil {
+				err = st.Delete(ctx, bm.ID)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to remove %s: %v\n", ref, err)
+				failed++
+				continue
+			}
+			fmt.Printf("Removed %s  %s\n", store.ShortID(bm.ID), bm.Title)
+		}
+
+		if failed > 0 {
+			return cli.Exit("", 1)
+		}
+		return nil
+	}
+}
+
+func printBookmark(bm models.Bookmark) {
+	fmt.Printf("ID:          %s\n", bm.ID)
+	fmt.Printf("Title:       %s\n", bm.Title)
+	fmt.Printf("URL:         %s\n", bm.URL)
+	if bm.Description != "" {
+		fmt.Printf("Description: %s\n", bm.Description)
+	}
+	if len(bm.Tags) > 0 {
+		fmt.Printf("Tags:        %s\n", strings.Join(bm.Tags, ", "))
+	}
+	if bm.Folder != "" {
+		fmt.Printf("Folder:      %s\n", bm.Folder)
+	}
+	if len(bm.Sources) > 0 {
+		fmt.Printf("Sources:     %s\n", strings.Join(bm.Sources, ", "))
+	}
+	fmt.Printf("Created:     %s\n", formatTime(bm.CreatedAt))
+	if bm.UpdatedAt > 0 {
+		fmt.Printf("Updated:     %s\n", formatTime(bm.UpdatedAt))
+	}
+}
+
+func formatTime(unix int64) string {
+	return time.Unix(unix, 0).Format("2006-01-02 15:04")
+}
+
+// RepairIDsCommand gives every bookmark the ID of its canonical URL,
+// merging bookmarks that turn out to share one
+func RepairIDsCommand(st store.Store) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		ctx := context.Background()
+		all, err := st.List(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list bookmarks: %v", err)
+		}
+
+		changed, merged, err := store.PlanRekey(all)
+		if err != nil {
+			return cli.Exit(err.Error(), 1)
+		}
+		if changed == 0 {
+			fmt.Println("All bookmark IDs are up to date")
+			return nil
+		}
+		if c.Bool("dry-run") {
+			fmt.Printf("Would rewrite %d IDs, merging %d bookmarks\n", changed, merged)
+			return nil
+		}
+
+		bar := progressbar.Default(int64(len(all)-merged), "Rewriting IDs")
+		merged, err = st.Rekey(ctx, func(done, total int) {
+			bar.Set(done)
+		})
+		bar.Finish()
+		if err != nil {
+			return fmt.Errorf("failed to rewrite IDs: %v", err)
+		}
+		fmt.Printf("Rewrote %d IDs, merged %d bookmarks\n", changed, merged)
+		return nil
+	}
+}
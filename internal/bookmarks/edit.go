@@ -0,0 +1,201 @@
+package bookmarks
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/abhijith/bookmark-cli/internal/models"
+	"github.com/abhijith/bookmark-cli/internal/store"
+	"github.com/abhijith/bookmark-cli/internal/urlnorm"
+	"github.com/urfave/cli/v2"
+)
+
+// editable is the part of a bookmark shown in the editor; the ID and
+// timestamps are maintained by the store
+type editable struct {
+	URL         string   `json:"url"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+	Folder      string   `json:"folder"`
+}
+
+// EditCommand opens a bookmark in $EDITOR as JSON and saves it once it validates
+func EditCommand(st store.Store) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		if c.NArg() < 1 {
+			return cli.Exit("Missing bookmark ID", 1)
+		}
+
+		ctx := context.Background()
+		bm, err := Resolve(ctx, st, c.Args().First())
+		if err != nil {
+			return cli.Exit(err.Error(), 1)
+		}
+
+		original, err := json.MarshalIndent(editable{bm.URL, bm.Title, bm.Description, bm.Tags, bm.Folder}, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		text := append(original, '\n')
+		for {
+			text, err = runEditor(text)
+			if err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+
+			edited, err := parseEdit(text)
+			if err == nil {
+				var updated models.Bookmark
+				updated, err = save(ctx, st, bm, edited)
+				if err == nil {
+					if updated.ID == "" {
+						fmt.Println("No changes")
+					} else {
+						fmt.Printf("Saved %s\n", store.ShortID(updated.ID))
+					}
+					return nil
+				}
+			}
+
+			fmt.Fprintf(os.Stderr, "Invalid bookmark: %v\n", err)
+			if !confirm("Edit again? [Y/n] ") {
+				return cli.Exit("Edit aborted, nothing saved", 1)
+			}
+		}
+	}
+}
+
+// runEditor lets the user edit text in $VISUAL or $EDITOR and returns the result
+func runEditor(text []byte) ([]byte, error) {
+	f, err := os.CreateTemp("", "bookmark-*.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(text); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to write temp file: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write temp file: %v", err)
+	}
+
+	// The editor may carry arguments, e.g. "code --wait"
+	args := strings.Fields(editor())
+	cmd := exec.Command(args[0], append(args[1:], f.Name())...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("editor failed: %v", err)
+	}
+
+	edited, err := os.ReadFile(f.Name())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read edited file: %v", err)
+	}
+	return edited, nil
+}
+
+func editor() string {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if e := strings.TrimSpace(os.Getenv(name)); e != "" {
+			return e
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return "notepad"
+	}
+	return "vi"
+}
+
+// parseEdit decodes and validates the edited JSON
+func parseEdit(text []byte) (editable, error) {
+	var e editable
+	dec := json.NewDecoder(bytes.NewReader(text))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&e); err != nil {
+		return e, err
+	}
+
+	e.URL = strings.TrimSpace(e.URL)
+	u, err := url.Parse(e.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return e, fmt.Errorf("url must be an absolute http or https URL")
+	}
+	e.Title = strings.TrimSpace(e.Title)
+	if e.Title == "" {
+		return e, fmt.Errorf("title must not be empty")
+	}
+	e.Description = strings.TrimSpace(e.Description)
+	e.Folder = strings.Trim(strings.TrimSpace(e.Folder), "/")
+
+	var tags []string
+	seen := make(map[string]bool)
+	for _, tag := range e.Tags {
+		if tag = strings.TrimSpace(tag); tag != "" && !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	e.Tags = tags
+	return e, nil
+}
+
+// save applies the edit to bm. A URL pointing to another page moves the
+// bookmark to the ID of the new URL. It returns a zero bookmark when nothing changed.
+func save(ctx context.Context, st store.Store, bm models.Bookmark, e editable) (models.Bookmark, error) {
+	if e.URL == bm.URL && e.Title == bm.Title && e.Description == bm.Description &&
+		e.Folder == bm.Folder && strings.Join(e.Tags, "\x00") == strings.Join(bm.Tags, "\x00") {
+		return models.Bookmark{}, nil
+	}
+
+	updated := bm
+	updated.URL, updated.Title, updated.Description, updated.Tags = e.URL, e.Title, e.Description, e.Tags
+	updated.Folder = e.Folder
+	updated.UpdatedAt = time.Now().Unix()
+
+	// A variant of the same page, e.g. http to https, keeps the ID, even a
+	// legacy one
+	if urlnorm.Canonical(e.URL) != urlnorm.Canonical(bm.URL) {
+		exists, err := st.HasURL(ctx, e.URL)
+		if err != nil {
+			return models.Bookmark{}, err
+		}
+		if exists {
+			return models.Bookmark{}, fmt.Errorf("%s is already bookmarked", e.URL)
+		}
+		updated.ID = store.NewID(e.URL)
+	}
+
+	if err := st.Put(ctx, updated); err != nil {
+		return models.Bookmark{}, fmt.Errorf("failed to save bookmark: %v", err)
+	}
+	if updated.ID != bm.ID {
+		if err := st.Delete(ctx, bm.ID); err != nil {
+			return models.Bookmark{}, fmt.Errorf("failed to remove old bookmark %s: %v", bm.ID, err)
+		}
+	}
+	return updated, nil
+}
+
+// confirm asks a yes/no question on the terminal, defaulting to yes
+func confirm(question string) bool {
+	fmt.Fprint(os.Stderr, question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && answer == "" {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "" || answer == "y" || answer == "yes"
+}
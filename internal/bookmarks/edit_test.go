@@ -0,0 +1,54 @@
+package bookmarks
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/abhijith/bookmark-cli/internal/models"
+	"github.com/abhijith/bookmark-cli/internal/store"
+)
+
+func TestSave(t *testing.T) {
+	ctx := context.Background()
+	st := store.NewMemoryStore()
+	legacy := models.Bookmark{ID: "legacy", URL: "http://www.example.com/page", Title: "Page", CreatedAt: 1}
+	other := models.Bookmark{ID: store.NewID("https://example.com/other"), URL: "https://example.com/other", Title: "Other", CreatedAt: 2}
+	for _, bm := range []models.Bookmark{legacy, other} {
+		if err := st.Put(ctx, bm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Another form of the same URL keeps the bookmark where it is
+	edit := editable{URL: "https://example.com/page", Title: "Page"}
+	bm, err := save(ctx, st, legacy, edit)
+	if err != nil {
+		t.Fatalf("save variant: %v", err)
+	}
+	if bm.ID != "legacy" || bm.URL != edit.URL {
+		t.Errorf("saved %+v, want the URL updated under the legacy ID", bm)
+	}
+
+	// A URL another bookmark has is refused
+	edit.URL = "https://www.example.com/other/"
+	if _, err := save(ctx, st, bm, edit); err == nil || !strings.Contains(err.Error(), "already bookmarked") {
+		t.Errorf("save onto a known URL error = %v, want already bookmarked", err)
+	}
+
+	// A new page moves the bookmark to the ID of its URL
+	edit.URL = "https://example.com/moved"
+	moved, err := save(ctx, st, bm, edit)
+	if err != nil {
+		t.Fatalf("save new URL: %v", err)
+	}
+	if moved.ID != store.NewID(edit.URL) {
+		t.Errorf("moved to ID %s, want %s", moved.ID, store.NewID(edit.URL))
+	}
+	if _, err := st.Get(ctx, "legacy"); err != store.ErrNotFound {
+		t.Errorf("old ID still stored: %v", err)
+	}
+	if ok, _ := st.HasURL(ctx, "https://example.com/page"); ok {
+		t.Error("HasURL still reports the old URL")
+	}
+}
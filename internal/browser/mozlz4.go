@@ -0,0 +1,104 @@
+package browser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// mozLz4Magic starts every mozlz4 file (.jsonlz4, .mozlz4, .baklz4)
+var mozLz4Magic = []byte("mozLz40\x00")
+
+// maxMozLz4Size bounds the decompressed size a file may declare
+const maxMozLz4Size = 256 << 20
+
+// isMozLz4 reports whether data is in Mozilla's LZ4 framing
+func isMozLz4(data []byte) bool {
+	return bytes.HasPrefix(data, mozLz4Magic)
+}
+
+// decodeMozLz4 decompresses a mozlz4 file: the magic, the decompressed size
+// as a little-endian uint32, then a single LZ4 block
+func decodeMozLz4(data []byte) ([]byte, error) {
+	if !isMozLz4(data) || len(data) < len(mozLz4Magic)+4 {
+		return nil, fmt.Errorf("not a mozlz4 file")
+	}
+	size := binary.LittleEndian.Uint32(data[len(mozLz4Magic):])
+	if size > maxMozLz4Size {
+		return nil, fmt.Errorf("mozlz4 file declares %d bytes, more than the %d allowed", size, maxMozLz4Size)
+	}
+	return lz4Block(data[len(mozLz4Magic)+4:], int(size))
+}
+
+// lz4Block decompresses one LZ4 block into exactly size bytes. A block is
+// a run of sequences, each a token, literals copied as they are and a
+// match copied from earlier output; the last sequence has no match.
+func lz4Block(src []byte, size int) ([]byte, error) {
+	dst := make([]byte, 0, size)
+	corrupt := func(what string) ([]byte, error) {
+		return nil, fmt.Errorf("corrupt LZ4 block: %s", what)
+	}
+
+	for i := 0; i < len(src); {
+		token := src[i]
+		i++
+
+		literals, n, ok := lz4Length(src[i:], int(token>>4))
+		if !ok {
+			return corrupt("truncated literal length")
+		}
+		i += n
+		if literals > len(src)-i || literals > size-len(dst) {
+			return corrupt("literals overrun")
+		}
+		dst = append(dst, src[i:i+literals]...)
+		i += literals
+		if i == len(src) {
+			break
+		}
+
+		if len(src)-i < 2 {
+			return corrupt("truncated match offset")
+		}
+		offset := int(binary.LittleEndian.Uint16(src[i:]))
+		i += 2
+		if offset == 0 || offset > len(dst) {
+			return corrupt("match offset out of range")
+		}
+		match, n, ok := lz4Length(src[i:], int(token&0x0f))
+		if !ok {
+			return corrupt("truncated match length")
+		}
+		i += n
+		match += 4 // the minimum match
+		if match > size-len(dst) {
+			return corrupt("match overrun")
+		}
+		// Byte by byte, as a match may overlap the bytes it produces
+		start := len(dst) - offset
+		for j := 0; j < match; j++ {
+			dst = append(dst, dst[start+j])
+		}
+	}
+
+	if len(dst) != size {
+		return nil, fmt.Errorf("corrupt LZ4 block: got %d bytes, want %d", len(dst), size)
+	}
+	return dst, nil
+}
+
+// lz4Length completes a length from the 4 bits in the token: 15 means more
+// bytes follow, each added until one is below 255. It returns the length
+// and the number of bytes read.
+func lz4Length(src []byte, length int) (int, int, bool) {
+	if length != 15 {
+		return length, 0, true
+	}
+	for n, b := range src {
+		length += int(b)
+		if b != 255 {
+			return length, n + 1, true
+		}
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,143 @@
+package browser
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/abhijith/bookmark-cli/internal/models"
+)
+
+// mozLz4 frames an LZ4 block as a mozlz4 file declaring size bytes
+func mozLz4(size int, block ...byte) []byte {
+	data := append([]byte(nil), mozLz4Magic...)
+	data = binary.LittleEndian.AppendUint32(data, uint32(size))
+	return append(data, block...)
+}
+
+func readTestdata(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestDecodeMozLz4(t *testing.T) {
+	// Compressed by the reference lz4 tool
+	got, err := decodeMozLz4(readTestdata(t, "firefox-backup.jsonlz4"))
+	if err != nil {
+		t.Fatalf("decodeMozLz4: %v", err)
+	}
+	if want := readTestdata(t, "firefox-backup.json"); !bytes.Equal(got, want) {
+		t.Errorf("decodeMozLz4 =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestLz4Block(t *testing.T) {
+	tests := []struct {
+		name  string
+		block []byte
+		want  string
+	}{
+		{"literals only", []byte{0x50, 'h', 'e', 'l', 'l', 'o'}, "hello"},
+		{"empty", []byte{0x00}, ""},
+		// "ab", then a match of 4+2 at offset 2 overlapping its own output,
+		// then the final literal
+		{"overlapping match", []byte{0x22, 'a', 'b', 2, 0, 0x10, '!'}, "abababab!"},
+		// 15+3 literals need an extra length byte
+		{"long literals", append([]byte{0xf0, 3}, []byte("abcdefghijklmnopqr")...), "abcdefghijklmnopqr"},
+		// A match of 4+15+255+1 repeats one byte 276 times
+		{"long match", []byte{0x1f, 'x', 1, 0, 255, 1, 0x00}, string(bytes.Repeat([]byte("x"), 276))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeMozLz4(mozLz4(len(tt.want), tt.block...))
+			if err != nil {
+				t.Fatalf("decodeMozLz4: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("decodeMozLz4 = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeMozLz4Errors(t *testing.T) {
+	tests := map[string][]byte{
+		"empty":                 nil,
+		"bad magic":             append([]byte("mozLz41\x00"), 5, 0, 0, 0, 0x50, 'h', 'e', 'l', 'l', 'o'),
+		"plain json":            []byte(`{"children": []}`),
+		"missing size":          []byte("mozLz40\x00\x05\x00"),
+		"huge size":             mozLz4(maxMozLz4Size+1, 0x00),
+		"size too small":        mozLz4(4, 0x50, 'h', 'e', 'l', 'l', 'o'),
+		"size too large":        mozLz4(6, 0x50, 'h', 'e', 'l', 'l', 'o'),
+		"truncated literals":    mozLz4(5, 0x50, 'h', 'e'),
+		"truncated literal len": mozLz4(20, 0xf0),
+		"truncated offset":      mozLz4(10, 0x24, 'a', 'b', 2),
+		"zero offset":           mozLz4(10, 0x24, 'a', 'b', 0, 0, 0x00),
+		"offset before start":   mozLz4(10, 0x24, 'a', 'b', 3, 0, 0x00),
+		"far offset":            mozLz4(10, 0x24, 'a', 'b', 0xff, 0xff, 0x00),
+		"truncated match len":   mozLz4(30, 0x2f, 'a', 'b', 2, 0, 255),
+		"match overrun":         mozLz4(5, 0x24, 'a', 'b', 2, 0, 0x00),
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got, err := decodeMozLz4(data); err == nil {
+				t.Errorf("decodeMozLz4 = %q, want an error", got)
+			}
+		})
+	}
+}
+
+func TestDecodeMozLz4Damaged(t *testing.T) {
+	data := readTestdata(t, "firefox-backup.jsonlz4")
+	size := len(readTestdata(t, "firefox-backup.json"))
+
+	// Every truncation must fail cleanly
+	for n := 0; n < len(data); n++ {
+		if _, err := decodeMozLz4(data[:n]); err == nil {
+			t.Errorf("decodeMozLz4 of the first %d bytes succeeded", n)
+		}
+	}
+
+	// Flipped bits may decode to something else but must never panic or
+	// produce more than the declared size
+	damaged := make([]byte, len(data))
+	for i := len(mozLz4Magic) + 4; i < len(data); i++ {
+		for _, mask := range []byte{0x01, 0x10, 0x80, 0xff} {
+			copy(damaged, data)
+			damaged[i] ^= mask
+			if got, err := decodeMozLz4(damaged); err == nil && len(got) != size {
+				t.Errorf("byte %d ^ %#x decoded to %d bytes", i, mask, len(got))
+			}
+		}
+	}
+}
+
+func TestFirefoxSourceBackup(t *testing.T) {
+	want := []models.Bookmark{
+		{URL: "https://support.mozilla.org/products/firefox", Title: "Get Help", Folder: "Bookmarks Menu/Mozilla Firefox", CreatedAt: 1700000011, UpdatedAt: 1700000012},
+		{URL: "https://support.mozilla.org/kb/customize-firefox-controls-buttons-and-toolbars", Title: "Customize Firefox", Folder: "Bookmarks Menu/Mozilla Firefox", CreatedAt: 1700000013, UpdatedAt: 1700000014},
+		{URL: "https://go.dev/", Title: "The Go Programming Language", Tags: []string{"go", "dev"}, Folder: "Bookmarks Toolbar", CreatedAt: 1700000100, UpdatedAt: 1700000200},
+		{URL: "place:sort=8&maxResults=10", Title: "Most Visited", Folder: "Bookmarks Toolbar", CreatedAt: 1700000110, UpdatedAt: 1700000110},
+		{URL: "https://example.com/menu", Title: `Café "menu"`, Folder: "Other Bookmarks", CreatedAt: 1700000300, UpdatedAt: 1700000400},
+	}
+
+	for _, name := range []string{"firefox-backup.jsonlz4", "firefox-backup.json"} {
+		t.Run(name, func(t *testing.T) {
+			got, err := FirefoxSource{Path: filepath.Join("testdata", name)}.Read(context.Background())
+			if err != nil {
+				t.Fatalf("Read: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Read =\n%+v\nwant\n%+v", got, want)
+			}
+		})
+	}
+}
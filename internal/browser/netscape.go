@@ -0,0 +1,158 @@
+package browser
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/abhijith/bookmark-cli/internal/models"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// What the text being read belongs to
+const (
+	captureNone = iota
+	captureTitle
+	captureHeading
+	captureDescription
+)
+
+// parseNetscape reads a Netscape bookmark file, the HTML export format of
+// every browser and most bookmarking services. Each <DL> is a folder named
+// by the <H3> before it, each <A> a bookmark and a <DD> after it its
+// description. ICON data is ignored.
+func parseNetscape(r io.Reader) ([]models.Bookmark, error) {
+	var (
+		bookmarks []models.Bookmark
+		folders   []string // one name per open <DL>, "" for the top level
+		heading   string   // the last <H3>, which names the next <DL>
+		last      = -1     // the bookmark a <DD> would describe
+		capture   = captureNone
+		text      strings.Builder
+	)
+
+	// finish stores the text read since the last capture began
+	finish := func() {
+		s := strings.Join(strings.Fields(text.String()), " ")
+		switch capture {
+		case captureTitle:
+			bookmarks[last].Title = s
+		case captureHeading:
+			heading = s
+		case captureDescription:
+			// Descriptions keep their line breaks
+			var lines []string
+			for _, line := range strings.Split(text.String(), "\n") {
+				if line = strings.Join(strings.Fields(line), " "); line != "" {
+					lines = append(lines, line)
+				}
+			}
+			bookmarks[last].Description = strings.Join(lines, "\n")
+			last = -1
+		}
+		capture = captureNone
+		text.Reset()
+	}
+
+	z := html.NewTokenizer(r)
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if err := z.Err(); err != io.EOF {
+				return nil, fmt.Errorf("failed to parse bookmark file: %v", err)
+			}
+			finish()
+			return bookmarks, nil
+		case html.TextToken:
+			if capture != captureNone {
+				text.Write(z.Text())
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+			switch t.DataAtom {
+			case atom.A:
+				finish()
+				bookmarks = append(bookmarks, netscapeBookmark(t.Attr, folderPath(folders)))
+				last = len(bookmarks) - 1
+				capture = captureTitle
+			case atom.H3:
+				finish()
+				last = -1
+				capture = captureHeading
+			case atom.Dd:
+				finish()
+				if last >= 0 {
+					capture = captureDescription
+				}
+			case atom.Dl:
+				finish()
+				folders = append(folders, heading)
+				heading = ""
+				last = -1
+			case atom.Dt, atom.H1:
+				finish()
+			}
+		case html.EndTagToken:
+			switch z.Token().DataAtom {
+			case atom.A, atom.H3, atom.Dd:
+				finish()
+			case atom.Dl:
+				finish()
+				if len(folders) > 0 {
+					folders = folders[:len(folders)-1]
+				}
+				last = -1
+			}
+		}
+	}
+}
+
+// netscapeBookmark reads the attributes of an <A> tag
+func netscapeBookmark(attrs []html.Attribute, folder string) models.Bookmark {
+	bm := models.Bookmark{Folder: folder}
+	for _, a := range attrs {
+		switch a.Key {
+		case "href":
+			bm.URL = a.Val
+		case "add_date":
+			bm.CreatedAt = netscapeTime(a.Val)
+		case "last_modified":
+			bm.UpdatedAt = netscapeTime(a.Val)
+		case "tags":
+			for _, tag := range strings.Split(a.Val, ",") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					bm.Tags = append(bm.Tags, tag)
+				}
+			}
+		}
+	}
+	return bm
+}
+
+// netscapeTime parses a date attribute. The format says Unix seconds, but
+// some exporters write milliseconds or microseconds.
+func netscapeTime(s string) int64 {
+	t, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	switch {
+	case err != nil || t <= 0:
+		return 0
+	case t > 1e14:
+		return t / 1000000
+	case t > 1e11:
+		return t / 1000
+	}
+	return t
+}
+
+// folderPath joins the names of the open folders, skipping the unnamed top level
+func folderPath(folders []string) string {
+	var names []string
+	for _, name := range folders {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, "/")
+}
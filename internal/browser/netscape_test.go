@@ -0,0 +1,121 @@
+package browser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/abhijith/bookmark-cli/internal/models"
+)
+
+func TestParseNetscape(t *testing.T) {
+	tests := []struct {
+		file string
+		want []models.Bookmark
+	}{
+		{"chrome.html", []models.Bookmark{
+			{URL: "https://go.dev/", Title: "The Go Programming Language", Folder: "Bookmarks bar", CreatedAt: 1700000100},
+			{URL: "https://github.com/golang/go", Title: "GitHub - golang/go: The Go programming language", Folder: "Bookmarks bar/Dev", CreatedAt: 1700000250},
+			{URL: "https://news.ycombinator.com/", Title: "Hacker News", CreatedAt: 1700000400},
+		}},
+		{"firefox.html", []models.Bookmark{
+			{
+				URL:         "https://www.mozilla.org/en-US/firefox/",
+				Title:       "Firefox & You",
+				Description: `The browser "that" respects you`,
+				Tags:        []string{"browser", "mozilla"},
+				CreatedAt:   1700000000,
+				UpdatedAt:   1700000100,
+			},
+			{
+				URL:       "https://example.com/search?q=pasta&page=2",
+				Title:     "Pasta <fresh>",
+				Folder:    "Recipes",
+				CreatedAt: 1700000250,
+				UpdatedAt: 1700000260,
+			},
+			{URL: "place:sort=8&maxResults=10", Title: "Most Visited", Folder: "Bookmarks Toolbar", CreatedAt: 1700000450},
+		}},
+		{"safari.html", []models.Bookmark{
+			{URL: "https://www.apple.com/", Title: "Apple", Folder: "Favorites"},
+			{URL: "https://www.bbc.co.uk/news", Title: "BBC News – Home", Folder: "Favorites/News"},
+			{URL: "https://example.org/long-read", Title: "A Long Read", Folder: "Reading List"},
+		}},
+		{"pinboard.html", []models.Bookmark{
+			{
+				URL:         "https://blog.golang.org/pipelines",
+				Title:       "Go Concurrency Patterns: Pipelines",
+				Description: "Stages connected by channels.\nEach stage is a group of goroutines\nrunning the same function.",
+				Tags:        []string{"go", "concurrency", "patterns"},
+				CreatedAt:   1400000000,
+			},
+			{URL: "https://example.com/no-tags", Title: "Untagged", CreatedAt: 1400000100},
+			{URL: "https://example.com/spaces", Title: "Spaced tags", Tags: []string{"a", "b", "c"}, CreatedAt: 1400000200},
+		}},
+		{"raindrop.html", []models.Bookmark{
+			{
+				URL:         "https://doc.rust-lang.org/book/",
+				Title:       "The Rust Programming Language",
+				Description: "The official book.\nCovers 'ownership' & borrowing.",
+				Tags:        []string{"rust", "books"},
+				Folder:      "Reading/Rust & Go",
+				CreatedAt:   1650000100,
+				UpdatedAt:   1650000200,
+			},
+			{URL: "https://example.com/caf%C3%A9", Title: "Café ☕", Tags: []string{"food"}, Folder: "Reading", CreatedAt: 1650000300},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			got, err := parseNetscape(f)
+			if err != nil {
+				t.Fatalf("parseNetscape: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("parseNetscape returned %d bookmarks, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i := range got {
+				if !reflect.DeepEqual(got[i], tt.want[i]) {
+					t.Errorf("bookmark %d:\n got %+v\nwant %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNetscapeTime(t *testing.T) {
+	for in, want := range map[string]int64{
+		"1700000000":       1700000000, // seconds
+		"1700000000000":    1700000000, // milliseconds
+		"1700000000000000": 1700000000, // microseconds
+		" 1700000000 ":     1700000000,
+		"":                 0,
+		"-5":               0,
+		"soon":             0,
+	} {
+		if got := netscapeTime(in); got != want {
+			t.Errorf("netscapeTime(%q) = %d, want %d", in, got, want)
+		}
+	}
+}
+
+func TestParseNetscapeUnclosed(t *testing.T) {
+	// Truncated files still yield what was read
+	got, err := parseNetscape(strings.NewReader(`<DL><p><DT><H3>Folder</H3><DL><p><DT><A HREF="https://example.com/">Example`))
+	if err != nil {
+		t.Fatalf("parseNetscape: %v", err)
+	}
+	want := []models.Bookmark{{URL: "https://example.com/", Title: "Example", Folder: "Folder"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseNetscape = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,274 @@
+package browser
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/abhijith/bookmark-cli/internal/models"
+	_ "modernc.org/sqlite"
+)
+
+// GUIDs of the folders every places.sqlite has
+const (
+	placesRootGUID = "root________"
+	placesTagsGUID = "tags________"
+)
+
+// placesRootNames are the names Firefox shows for the top-level folders,
+// whose titles in the database are internal ones like "toolbar"
+var placesRootNames = map[string]string{
+	"menu________": "Bookmarks Menu",
+	"toolbar_____": "Bookmarks Toolbar",
+	"unfiled_____": "Other Bookmarks",
+	"mobile______": "Mobile Bookmarks",
+}
+
+// PlacesSource reads the places.sqlite database of a Firefox profile, or
+// of a browser built on Firefox such as Zen. The database is copied first,
+// as the browser keeps it locked while running.
+type PlacesSource struct {
+	Browser string
+	Path    string
+}
+
+func (s PlacesSource) Name() string {
+	return s.Browser
+}
+
+// placesEntry is a row of moz_bookmarks
+type placesEntry struct {
+	id, typ, parent     int64
+	title, guid, url    string
+	added, lastModified int64
+}
+
+// placesError is a failure to copy, open or query places.sqlite, as opposed
+// to one storing the bookmarks read from it
+type placesError struct {
+	err error
+}
+
+func (e placesError) Error() string {
+	return e.err.Error()
+}
+
+func (e placesError) Unwrap() error {
+	return e.err
+}
+
+func (s PlacesSource) Read(ctx context.Context) ([]models.Bookmark, error) {
+	bookmarks, err := s.read(ctx)
+	if err != nil {
+		return nil, placesError{err}
+	}
+	return bookmarks, nil
+}
+
+func (s PlacesSource) read(ctx context.Context) ([]models.Bookmark, error) {
+	dir, err := copyPlaces(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := sql.Open("sqlite", filepath.Join(dir, "places.sqlite"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", s.Path, err)
+	}
+	defer db.Close()
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT b.id, b.type, b.parent, COALESCE(b.title, p.title), b.guid, p.url, b.dateAdded, b.lastModified
+		FROM moz_bookmarks b
+		LEFT JOIN moz_places p ON b.fk = p.id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bookmarks from %s: %v", s.Path, err)
+	}
+	defer rows.Close()
+
+	var entries []placesEntry
+	byID := make(map[int64]placesEntry)
+	for rows.Next() {
+		var e placesEntry
+		var title, guid, url sql.NullString
+		var added, lastModified sql.NullInt64
+		if err := rows.Scan(&e.id, &e.typ, &e.parent, &title, &guid, &url, &added, &lastModified); err != nil {
+			return nil, fmt.Errorf("failed to read bookmarks from %s: %v", s.Path, err)
+		}
+		e.title, e.guid, e.url = title.String, guid.String, url.String
+		e.added, e.lastModified = added.Int64/1000000, lastModified.Int64/1000000 // microseconds
+		entries = append(entries, e)
+		byID[e.id] = e
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read bookmarks from %s: %v", s.Path, err)
+	}
+
+	// A tag is a folder under the tags root holding a bookmark of each
+	// tagged URL
+	tags := make(map[string][]string)
+	isTag := func(e placesEntry) bool {
+		return byID[byID[e.parent].parent].guid == placesTagsGUID
+	}
+	for _, e := range entries {
+		if e.typ == 1 && e.url != "" && isTag(e) {
+			tags[e.url] = append(tags[e.url], byID[e.parent].title)
+		}
+	}
+
+	var bookmarks []models.Bookmark
+	for _, e := range entries {
+		if e.typ != 1 || e.url == "" || isTag(e) {
+			continue
+		}
+		bookmarks = append(bookmarks, models.Bookmark{
+			URL:       e.url,
+			Title:     e.title,
+			Tags:      tags[e.url],
+			Folder:    placesFolder(byID, e.parent),
+			CreatedAt: e.added,
+			UpdatedAt: e.lastModified,
+		})
+	}
+	return bookmarks, nil
+}
+
+// placesFolder returns the full path of the folder with the given id
+func placesFolder(byID map[int64]placesEntry, id int64) string {
+	var names []string
+	// The depth limit guards against a corrupt database with a parent loop
+	for depth := 0; depth < 100; depth++ {
+		folder, ok := byID[id]
+		if !ok || folder.guid == placesRootGUID {
+			break
+		}
+		name := folder.title
+		if root, ok := placesRootNames[folder.guid]; ok {
+			name = root
+		}
+		names = append([]string{name}, names...)
+		id = folder.parent
+	}
+	return strings.Join(names, "/")
+}
+
+// copyPlaces copies places.sqlite and its write-ahead log, which holds
+// recent changes until the browser checkpoints it, to a temporary directory
+func copyPlaces(path string) (string, error) {
+	dir, err := os.MkdirTemp("", "bm-places-")
+	if err != nil {
+		return "", err
+	}
+	for _, suffix := range []string{"", "-wal"} {
+		err := copyFile(path+suffix, filepath.Join(dir, "places.sqlite"+suffix))
+		if err != nil && !(suffix != "" && os.IsNotExist(err)) {
+			os.RemoveAll(dir)
+			return "", fmt.Errorf("failed to copy %s: %v", path+suffix, err)
+		}
+	}
+	return dir, nil
+}
+
+func copyFile(from, to string) error {
+	in, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(to)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// findPlaces returns the places.sqlite of the default profile in dir, a
+// Firefox-style data directory, or "" if there is none. The default comes
+// from profiles.ini; without one every profile folder is tried.
+func findPlaces(dir string) string {
+	for _, profile := range iniProfiles(filepath.Join(dir, "profiles.ini")) {
+		if !filepath.IsAbs(profile) {
+			profile = filepath.Join(dir, profile)
+		}
+		places := filepath.Join(profile, "places.sqlite")
+		if _, err := os.Stat(places); err == nil {
+			return places
+		}
+	}
+
+	for _, sub := range []string{"Profiles", "profiles", ""} {
+		profiles, err := os.ReadDir(filepath.Join(dir, sub))
+		if err != nil {
+			continue
+		}
+		for _, profile := range profiles {
+			places := filepath.Join(dir, sub, profile.Name(), "places.sqlite")
+			if _, err := os.Stat(places); profile.IsDir() && err == nil {
+				return places
+			}
+		}
+	}
+	return ""
+}
+
+// iniProfiles lists the profile paths in a profiles.ini, most likely
+// default first: those an [Install] section uses, then the one marked
+// Default=1, then the rest. Relative paths are relative to the ini file.
+func iniProfiles(path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var installs, defaults, others []string
+	section := ""
+	profile := map[string]string{}
+	flush := func() {
+		if strings.HasPrefix(section, "Profile") && profile["Path"] != "" {
+			p := filepath.FromSlash(profile["Path"])
+			if profile["Default"] == "1" {
+				defaults = append(defaults, p)
+			} else {
+				others = append(others, p)
+			}
+		}
+		profile = map[string]string{}
+	}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			flush()
+			section = line[1 : len(line)-1]
+		default:
+			key, value, ok := strings.Cut(line, "=")
+			if !ok {
+				continue
+			}
+			key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+			if strings.HasPrefix(section, "Install") && key == "Default" {
+				installs = append(installs, filepath.FromSlash(value))
+			} else {
+				profile[key] = value
+			}
+		}
+	}
+	flush()
+	return append(append(installs, defaults...), others...)
+}
@@ -0,0 +1,69 @@
+package browser
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/abhijith/bookmark-cli/internal/models"
+)
+
+func TestPlacesSourceRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "places.sqlite")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		`CREATE TABLE moz_places (id INTEGER PRIMARY KEY, url TEXT, title TEXT)`,
+		`CREATE TABLE moz_bookmarks (id INTEGER PRIMARY KEY, type INTEGER, fk INTEGER, parent INTEGER,
+			title TEXT, guid TEXT, dateAdded INTEGER, lastModified INTEGER)`,
+		`INSERT INTO moz_places VALUES (1, 'https://go.dev/', 'Go'), (2, 'https://example.com/', 'Example')`,
+		`INSERT INTO moz_bookmarks VALUES
+			(1, 2, NULL, 0, '', 'root________', 0, 0),
+			(2, 2, NULL, 1, 'toolbar', 'toolbar_____', 0, 0),
+			(3, 2, NULL, 1, 'tags', 'tags________', 0, 0),
+			(4, 2, NULL, 2, 'Dev', 'folder000001', 0, 0),
+			(5, 1, 1, 4, NULL, 'bookmark0001', 1700000000000000, 1700000100000000),
+			(6, 1, 2, 2, 'Example site', 'bookmark0002', 1700000200000000, 0),
+			(7, 2, NULL, 3, 'golang', 'tagfolder001', 0, 0),
+			(8, 1, 1, 7, NULL, 'tagentry0001', 0, 0)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Close()
+
+	got, err := PlacesSource{Browser: "firefox", Path: path}.Read(context.Background())
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	want := []models.Bookmark{
+		{URL: "https://go.dev/", Title: "Go", Tags: []string{"golang"}, Folder: "Bookmarks Toolbar/Dev", CreatedAt: 1700000000, UpdatedAt: 1700000100},
+		{URL: "https://example.com/", Title: "Example site", Folder: "Bookmarks Toolbar", CreatedAt: 1700000200},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Read =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestPlacesSourceUnreadable(t *testing.T) {
+	dir := t.TempDir()
+	corrupt := filepath.Join(dir, "places.sqlite")
+	if err := os.WriteFile(corrupt, []byte("not a database"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{corrupt, filepath.Join(dir, "missing.sqlite")} {
+		_, err := PlacesSource{Browser: "firefox", Path: path}.Read(context.Background())
+		var unreadable placesError
+		if !errors.As(err, &unreadable) {
+			t.Errorf("Read(%s) error = %v, want a placesError", filepath.Base(path), err)
+		}
+	}
+}
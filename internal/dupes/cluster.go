@@ -0,0 +1,385 @@
+package dupes
+
+import (
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/abhijith/bookmark-cli/internal/index"
+	"github.com/abhijith/bookmark-cli/internal/models"
+	"github.com/abhijith/bookmark-cli/internal/urlnorm"
+)
+
+// Scores given to each signal; a pair matching several combines them, so
+// two weak signals together are stronger than either alone
+const (
+	scoreSameURL  = 1.0
+	scoreVariant  = 0.95 // same page on a mobile subdomain or AMP path
+	scoreQuery    = 0.55 // same host and path, different query string; often a different page
+	scoreSiteName = 0.75 // identical title on the same site
+	scoreTitle    = 0.6  // identical title on different sites
+	scorePath     = 0.7  // scaled by how many path words the two share
+
+	// minPathSimilarity is the share of path words two URLs on one host
+	// must have in common to be compared at all
+	minPathSimilarity = 0.75
+	// minTitleSimilarity is the same for title words; similar titles are
+	// scored like identical ones, scaled by the share
+	minTitleSimilarity = 0.7
+	// maxTitleGroup skips titles shared by more bookmarks than this, such
+	// as "Home" or a site name used on every page
+	maxTitleGroup = 10
+	// maxPathGroup stops a path word from pairing up more bookmarks than
+	// this on one host
+	maxPathGroup = 200
+)
+
+// mobilePrefixes are host labels of mobile editions of a site
+var mobilePrefixes = []string{"m.", "mobile.", "amp."}
+
+// Cluster is a group of bookmarks that are probably the same page
+type Cluster struct {
+	Bookmarks []models.Bookmark
+	// Confidence is the weakest link that joined the cluster, from 0 to 1
+	Confidence float64
+	Reasons    []string
+}
+
+// pair collects the evidence that two bookmarks are duplicates
+type pair struct {
+	a, b    int
+	score   float64
+	reasons []string
+}
+
+// key identifies the parts of a bookmark URL compared by the detector
+type key struct {
+	canonical string
+	host      string // without mobile prefixes
+	path      string // without AMP segments
+	query     string
+	variant   bool // host or path had a mobile or AMP marker
+}
+
+func parseKey(rawURL string) key {
+	k := key{canonical: urlnorm.Canonical(rawURL)}
+	u, err := url.Parse(k.canonical)
+	if err != nil || u.Host == "" {
+		return k
+	}
+
+	k.host = u.Host
+	for _, prefix := range mobilePrefixes {
+		if strings.HasPrefix(k.host, prefix) {
+			k.host = strings.TrimPrefix(k.host, prefix)
+			k.variant = true
+			break
+		}
+	}
+
+	var segments []string
+	for _, segment := range strings.Split(u.Path, "/") {
+		if segment == "amp" {
+			k.variant = true
+			continue
+		}
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	k.path = strings.Join(segments, "/")
+	k.query = u.RawQuery
+	return k
+}
+
+// Find clusters bookmarks that are likely duplicates. Pairs scoring below
+// min are ignored; clusters are returned most confident first.
+func Find(bookmarks []models.Bookmark, min float64) []Cluster {
+	keys := make([]key, len(bookmarks))
+	for i, bm := range bookmarks {
+		keys[i] = parseKey(bm.URL)
+	}
+
+	pairs := make(map[[2]int]*pair)
+	add := func(a, b int, score float64, reason string) {
+		if a > b {
+			a, b = b, a
+		}
+		p, ok := pairs[[2]int{a, b}]
+		if !ok {
+			p = &pair{a: a, b: b}
+			pairs[[2]int{a, b}] = p
+		}
+		p.score = 1 - (1-p.score)*(1-score)
+		p.reasons = append(p.reasons, reason)
+	}
+
+	// Same page: host and path agree once mobile and AMP markers are gone
+	for _, group := range groupBy(len(bookmarks), func(i int) string {
+		if keys[i].host == "" {
+			return ""
+		}
+		return keys[i].host + "/" + keys[i].path
+	}) {
+		eachPair(group, func(a, b int) {
+			ka, kb := keys[a], keys[b]
+			switch {
+			case ka.canonical == kb.canonical:
+				add(a, b, scoreSameURL, "same URL")
+			case ka.query == kb.query:
+				add(a, b, scoreVariant, "mobile or AMP version")
+			default:
+				add(a, b, scoreQuery, "differs only by query string")
+			}
+		})
+	}
+
+	// Identical titles, ignoring case and punctuation
+	for _, group := range groupBy(len(bookmarks), func(i int) string {
+		return titleKey(bookmarks[i])
+	}) {
+		if len(group) > maxTitleGroup {
+			continue
+		}
+		eachPair(group, func(a, b int) {
+			if keys[a].host == keys[b].host {
+				add(a, b, scoreSiteName, "same title on the same site")
+			} else {
+				add(a, b, scoreTitle, "same title")
+			}
+		})
+	}
+
+	// Similar titles, e.g. "Go modules" and "Go modules - The Go Blog"
+	titles := make([]string, len(bookmarks))
+	all := make([]int, len(bookmarks))
+	for i, bm := range bookmarks {
+		titles[i] = titleKey(bm)
+		all[i] = i
+	}
+	similarWords(all, minTitleSimilarity, maxTitleGroup, func(i int) string {
+		return titles[i]
+	}, func(a, b int) bool {
+		return titles[a] == titles[b]
+	}, func(a, b int, similarity float64) {
+		if keys[a].host == keys[b].host {
+			add(a, b, scoreSiteName*similarity, "similar title on the same site")
+		} else {
+			add(a, b, scoreTitle*similarity, "similar title")
+		}
+	})
+
+	// Similar paths on one host, e.g. /blog/my-post and /blog/my_post.html
+	for _, group := range groupBy(len(bookmarks), func(i int) string {
+		return keys[i].host
+	}) {
+		similarWords(group, minPathSimilarity, maxPathGroup, func(i int) string {
+			return keys[i].path
+		}, func(a, b int) bool {
+			return keys[a].path == keys[b].path
+		}, func(a, b int, similarity float64) {
+			add(a, b, scorePath*similarity, "similar path")
+		})
+	}
+
+	return cluster(bookmarks, pairs, min)
+}
+
+// cluster joins pairs scoring at least min, strongest first, so each
+// cluster's confidence is the weakest pair needed to connect it
+func cluster(bookmarks []models.Bookmark, pairs map[[2]int]*pair, min float64) []Cluster {
+	var edges []*pair
+	for _, p := range pairs {
+		if p.score >= min {
+			edges = append(edges, p)
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].score != edges[j].score {
+			return edges[i].score > edges[j].score
+		}
+		return edges[i].a < edges[j].a || (edges[i].a == edges[j].a && edges[i].b < edges[j].b)
+	})
+
+	parent := make(map[int]int)
+	var root func(i int) int
+	root = func(i int) int {
+		if parent[i] == i {
+			return i
+		}
+		parent[i] = root(parent[i])
+		return parent[i]
+	}
+
+	confidence := make(map[int]float64)
+	reasons := make(map[int][]string)
+	for _, e := range edges {
+		for _, i := range []int{e.a, e.b} {
+			if _, ok := parent[i]; !ok {
+				parent[i] = i
+			}
+		}
+		ra, rb := root(e.a), root(e.b)
+		if ra == rb {
+			continue
+		}
+		score := e.score
+		for _, r := range []int{ra, rb} {
+			if c, ok := confidence[r]; ok && c < score {
+				score = c
+			}
+		}
+		parent[rb] = ra
+		confidence[ra] = score
+		reasons[ra] = append(append(reasons[ra], reasons[rb]...), e.reasons...)
+	}
+
+	members := make(map[int][]int)
+	for i := range parent {
+		members[root(i)] = append(members[root(i)], i)
+	}
+	clusters := make([]Cluster, 0, len(members))
+	for r, ids := range members {
+		sort.Ints(ids)
+		c := Cluster{Confidence: confidence[r], Reasons: unique(reasons[r])}
+		for _, i := range ids {
+			c.Bookmarks = append(c.Bookmarks, bookmarks[i])
+		}
+		sort.SliceStable(c.Bookmarks, func(i, j int) bool {
+			return c.Bookmarks[i].CreatedAt < c.Bookmarks[j].CreatedAt
+		})
+		clusters = append(clusters, c)
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		if clusters[i].Confidence != clusters[j].Confidence {
+			return clusters[i].Confidence > clusters[j].Confidence
+		}
+		return clusters[i].Bookmarks[0].ID < clusters[j].Bookmarks[0].ID
+	})
+	return clusters
+}
+
+// groupBy returns the indexes sharing a non-empty key, in groups of two or more
+func groupBy(n int, keyOf func(i int) string) [][]int {
+	byKey := make(map[string][]int)
+	for i := 0; i < n; i++ {
+		if k := keyOf(i); k != "" {
+			byKey[k] = append(byKey[k], i)
+		}
+	}
+	var groups [][]int
+	for _, group := range byKey {
+		if len(group) > 1 {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+func eachPair(group []int, fn func(a, b int)) {
+	for i := 0; i < len(group); i++ {
+		for j := i + 1; j < len(group); j++ {
+			fn(group[i], group[j])
+		}
+	}
+}
+
+// titleKey normalises a title for comparison. Short titles and titles that
+// are just the URL say too little to match on.
+func titleKey(bm models.Bookmark) string {
+	if bm.Title == bm.URL {
+		return ""
+	}
+	words := index.Tokenize(bm.Title)
+	title := strings.Join(words, " ")
+	if len(words) < 2 || len(title) < 8 {
+		return ""
+	}
+	return title
+}
+
+// similarWords calls fn for every pair of bookmarks in group whose text, a
+// path or a title, has at least min of its words in common with the other's.
+// Pairs that are the same already count under another signal and are left
+// out. Comparing every pair would be quadratic on big groups, so only pairs
+// sharing one of the rarest words of either text are compared: texts that
+// similar cannot differ in all of them (prefix filtering). A word shared by
+// maxGroup bookmarks or more pairs up no further ones.
+func similarWords(group []int, min float64, maxGroup int, text func(i int) string, same func(a, b int) bool, fn func(a, b int, similarity float64)) {
+	words := make(map[int][]string, len(group))
+	freq := make(map[string]int)
+	for _, i := range group {
+		if tokens := index.Tokenize(text(i)); len(tokens) >= 2 {
+			words[i] = unique(tokens)
+			sort.Strings(words[i])
+			for _, w := range words[i] {
+				freq[w]++
+			}
+		}
+	}
+
+	postings := make(map[string][]int)
+	for _, i := range group {
+		if words[i] == nil {
+			continue
+		}
+		rarest := append([]string(nil), words[i]...)
+		sort.Slice(rarest, func(x, y int) bool {
+			if freq[rarest[x]] != freq[rarest[y]] {
+				return freq[rarest[x]] < freq[rarest[y]]
+			}
+			return rarest[x] < rarest[y]
+		})
+		n := len(rarest)
+		prefix := n - int(math.Ceil(min*float64(n))) + 1
+
+		compared := make(map[int]bool)
+		for _, w := range rarest[:prefix] {
+			if len(postings[w]) >= maxGroup {
+				continue
+			}
+			for _, j := range postings[w] {
+				if compared[j] || same(i, j) {
+					continue
+				}
+				compared[j] = true
+				if similarity := jaccard(words[i], words[j]); similarity >= min {
+					fn(j, i, similarity)
+				}
+			}
+			postings[w] = append(postings[w], i)
+		}
+	}
+}
+
+// jaccard returns the share of distinct words a and b have in common; both
+// must be sorted and free of repeats
+func jaccard(a, b []string) float64 {
+	shared := 0
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			shared++
+			i++
+			j++
+		case a[i] < b[j]:
+			i++
+		default:
+			j++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+func unique(items []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, item := range items {
+		if !seen[item] {
+			seen[item] = true
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -0,0 +1,71 @@
+package dupes
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/abhijith/bookmark-cli/internal/models"
+)
+
+func TestFindSimilarPaths(t *testing.T) {
+	bookmarks := []models.Bookmark{
+		{ID: "1", URL: "https://example.com/blog/my-first-post", Title: "One"},
+		{ID: "2", URL: "https://example.com/blog/my_first_post.html", Title: "Two"},
+		{ID: "3", URL: "https://example.com/blog/another-post", Title: "Three"},
+		{ID: "4", URL: "https://other.com/blog/my-first-post", Title: "Four"},
+	}
+
+	clusters := Find(bookmarks, 0.4)
+	if len(clusters) != 1 {
+		t.Fatalf("Find returned %d clusters, want 1: %+v", len(clusters), clusters)
+	}
+	var ids []string
+	for _, bm := range clusters[0].Bookmarks {
+		ids = append(ids, bm.ID)
+	}
+	if fmt.Sprint(ids) != "[1 2]" {
+		t.Errorf("cluster = %v, want [1 2]", ids)
+	}
+}
+
+func TestFindLargeHost(t *testing.T) {
+	// Every path shares "wiki" and "repo"; only the article pairs are similar
+	var bookmarks []models.Bookmark
+	for i := 0; i < 10000; i++ {
+		bookmarks = append(bookmarks, models.Bookmark{
+			ID:    fmt.Sprint(i),
+			URL:   fmt.Sprintf("https://github.com/user%d/repo/wiki/article-%d", i/2, i/2),
+			Title: fmt.Sprintf("Page %d", i),
+		})
+	}
+
+	clusters := Find(bookmarks, 0.4)
+	if len(clusters) != 5000 {
+		t.Fatalf("Find returned %d clusters, want 5000", len(clusters))
+	}
+}
+
+func TestFindSimilarTitles(t *testing.T) {
+	bookmarks := []models.Bookmark{
+		{ID: "1", URL: "https://go.dev/blog/using-go-modules", Title: "Using Go Modules"},
+		{ID: "2", URL: "https://go.dev/doc/modules/intro", Title: "Using Go Modules | Go Blog"},
+		{ID: "3", URL: "https://go.dev/blog/go-modules-v2", Title: "Go Modules: v2 and Beyond"},
+		{ID: "4", URL: "https://example.com/notes/go", Title: "Using Go Modules!"},
+		{ID: "5", URL: "https://example.com/notes/rust", Title: "Using Rust Crates"},
+	}
+
+	clusters := Find(bookmarks, 0.4)
+	if len(clusters) != 1 {
+		t.Fatalf("Find returned %d clusters, want 1: %+v", len(clusters), clusters)
+	}
+	var ids []string
+	for _, bm := range clusters[0].Bookmarks {
+		ids = append(ids, bm.ID)
+	}
+	if fmt.Sprint(ids) != "[1 2 4]" {
+		t.Errorf("cluster = %v, want [1 2 4]", ids)
+	}
+	if fmt.Sprint(clusters[0].Reasons) != "[same title similar title on the same site]" {
+		t.Errorf("reasons = %v", clusters[0].Reasons)
+	}
+}
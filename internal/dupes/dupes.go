@@ -0,0 +1,139 @@
+package dupes
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abhijith/bookmark-cli/internal/models"
+	"github.com/abhijith/bookmark-cli/internal/store"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/term"
+)
+
+// FindCommand lists clusters of likely duplicates and merges them, either
+// cluster by cluster on the terminal or, with --auto, every cluster at or
+// above --threshold
+func FindCommand(st store.Store) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		ctx := context.Background()
+		bookmarks, err := st.List(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list bookmarks: %v", err)
+		}
+
+		threshold, min := c.Float64("threshold"), c.Float64("min")
+		if min > threshold {
+			min = threshold
+		}
+		clusters := Find(bookmarks, min)
+		if len(clusters) == 0 {
+			fmt.Println("No likely duplicates found")
+			return nil
+		}
+
+		auto := c.Bool("auto")
+		interactive := !auto && term.IsTerminal(int(os.Stdin.Fd()))
+		strategy := store.DefaultMergeStrategy()
+		in := bufio.NewReader(os.Stdin)
+
+		merged, removed := 0, 0
+	loop:
+		for i, cl := range clusters {
+			printCluster(i+1, len(clusters), cl)
+
+			keep := -1
+			switch {
+			case auto && cl.Confidence >= threshold:
+				keep = 0
+			case interactive:
+				var quit bool
+				if keep, quit = ask(in, len(cl.Bookmarks)); quit {
+					break loop
+				}
+			}
+			if keep < 0 {
+				fmt.Println()
+				continue
+			}
+
+			bm, err := mergeCluster(ctx, st, cl.Bookmarks, keep, strategy)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Merged %d bookmarks into %s\n\n", len(cl.Bookmarks), store.ShortID(bm.ID))
+			merged++
+			removed += len(cl.Bookmarks) - 1
+		}
+
+		switch {
+		case merged > 0:
+			fmt.Printf("Merged %d of %d clusters, removing %d bookmarks\n", merged, len(clusters), removed)
+		case !auto && !interactive:
+			fmt.Printf("Found %d clusters; run in a terminal or with --auto to merge them\n", len(clusters))
+		default:
+			fmt.Printf("Found %d clusters, none merged\n", len(clusters))
+		}
+		return nil
+	}
+}
+
+func printCluster(n, total int, cl Cluster) {
+	fmt.Printf("Cluster %d/%d, confidence %.0f%% (%s)\n", n, total, cl.Confidence*100, strings.Join(cl.Reasons, ", "))
+	for i, bm := range cl.Bookmarks {
+		fmt.Printf("  %d. %s  %s\n", i+1, store.ShortID(bm.ID), bm.Title)
+		fmt.Printf("     %s  (added %s)\n", bm.URL, time.Unix(bm.CreatedAt, 0).Format("2006-01-02"))
+	}
+}
+
+// ask prompts for what to do with a cluster of n bookmarks and returns the
+// index of the bookmark to keep, -1 to leave the cluster alone
+func ask(in *bufio.Reader, n int) (int, bool) {
+	for {
+		fmt.Printf("Merge into 1? [y]es, [1-%d] keep another, [n]o, [q]uit: ", n)
+		answer, err := in.ReadString('\n')
+		if err != nil && answer == "" {
+			return -1, true
+		}
+		switch answer = strings.ToLower(strings.TrimSpace(answer)); answer {
+		case "y", "yes":
+			return 0, false
+		case "", "n", "no":
+			return -1, false
+		case "q", "quit":
+			return -1, true
+		}
+		if i, err := strconv.Atoi(answer); err == nil && i >= 1 && i <= n {
+			return i - 1, false
+		}
+	}
+}
+
+// mergeCluster folds every bookmark of the cluster into the one at keep
+// and deletes the rest
+func mergeCluster(ctx context.Context, st store.Store, cluster []models.Bookmark, keep int, strategy store.MergeStrategy) (models.Bookmark, error) {
+	bm := cluster[keep]
+	for i, other := range cluster {
+		if i != keep {
+			bm = store.Absorb(bm, other, strategy)
+		}
+	}
+	bm.UpdatedAt = time.Now().Unix()
+
+	if err := st.Put(ctx, bm); err != nil {
+		return bm, fmt.Errorf("failed to save %s: %v", bm.ID, err)
+	}
+	for i, other := range cluster {
+		if i == keep || other.ID == bm.ID {
+			continue
+		}
+		if err := st.Delete(ctx, other.ID); err != nil && err != store.ErrNotFound {
+			return bm, fmt.Errorf("failed to remove %s: %v", other.ID, err)
+		}
+	}
+	return bm, nil
+}
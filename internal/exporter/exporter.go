@@ -0,0 +1,86 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/abhijith/bookmark-cli/internal/models"
+	"github.com/abhijith/bookmark-cli/internal/searcher"
+	"github.com/abhijith/bookmark-cli/internal/store"
+	"github.com/urfave/cli/v2"
+)
+
+// Options controls how bookmarks are written
+type Options struct {
+	// TagFolders files bookmarks without a folder under their first tag, in
+	// the formats that group by folder
+	TagFolders bool
+}
+
+// writer writes bookmarks, oldest first, in one export format
+type writer func(w io.Writer, bookmarks []models.Bookmark, opts Options) error
+
+var writers = map[string]writer{
+	"html":     writeNetscape,
+	"json":     writeJSON,
+	"jsonl":    writeJSONL,
+	"csv":      writeCSV,
+	"markdown": writeMarkdown,
+}
+
+// Command exports every bookmark, or those matching --query (or the
+// arguments), to stdout in format
+func Command(st store.Store, format string) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		write, ok := writers[format]
+		if !ok {
+			return cli.Exit(fmt.Sprintf("Unknown export format %q", format), 1)
+		}
+
+		query := c.String("query")
+		if query == "" {
+			query = strings.TrimSpace(strings.Join(c.Args().Slice(), " "))
+		}
+		bookmarks, err := selectBookmarks(st, query)
+		if err != nil {
+			return err
+		}
+
+		opts := Options{TagFolders: c.Bool("tag-folders")}
+		if err := write(os.Stdout, bookmarks, opts); err != nil {
+			return fmt.Errorf("export failed: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "Exported %d bookmarks\n", len(bookmarks))
+		return nil
+	}
+}
+
+// selectBookmarks returns the bookmarks matching query, or all of them when
+// it is empty, oldest first
+func selectBookmarks(st store.Store, query string) ([]models.Bookmark, error) {
+	if query == "" {
+		bookmarks, err := st.List(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("failed to list bookmarks: %v", err)
+		}
+		return bookmarks, nil
+	}
+
+	result, err := searcher.Find(st, query)
+	if err != nil {
+		return nil, cli.Exit(fmt.Sprintf("Invalid query: %v", err), 1)
+	}
+	// Notes go to stderr so they never end up in the export
+	for _, note := range result.Notes {
+		fmt.Fprintln(os.Stderr, note)
+	}
+	bookmarks := result.Bookmarks
+	sort.SliceStable(bookmarks, func(i, j int) bool {
+		return bookmarks[i].CreatedAt < bookmarks[j].CreatedAt
+	})
+	return bookmarks, nil
+}
@@ -0,0 +1,83 @@
+package exporter
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/abhijith/bookmark-cli/internal/models"
+	"github.com/abhijith/bookmark-cli/internal/searcher"
+)
+
+// writeJSON writes the {"bookmarks": [...]} document that `bm import` reads
+func writeJSON(w io.Writer, bookmarks []models.Bookmark, opts Options) error {
+	if bookmarks == nil {
+		bookmarks = []models.Bookmark{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Bookmarks []models.Bookmark `json:"bookmarks"`
+	}{bookmarks})
+}
+
+// writeJSONL writes one JSON bookmark per line
+func writeJSONL(w io.Writer, bookmarks []models.Bookmark, opts Options) error {
+	enc := json.NewEncoder(w)
+	for _, bm := range bookmarks {
+		if err := enc.Encode(bm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeCSV writes the same columns as `bm search --csv`
+func writeCSV(w io.Writer, bookmarks []models.Bookmark, opts Options) error {
+	return searcher.WriteCSV(w, bookmarks, ',')
+}
+
+// markdownEscaper escapes the characters that would end a link text early
+// or be read as HTML
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, `[`, `\[`, `]`, `\]`, `<`, `\<`, `>`, `\>`)
+
+// writeMarkdown writes a list of links with one heading per folder,
+// bookmarks without a folder first
+func writeMarkdown(w io.Writer, bookmarks []models.Bookmark, opts Options) error {
+	byFolder := make(map[string][]models.Bookmark)
+	for _, bm := range bookmarks {
+		folder := bm.Folder
+		if folder == "" && opts.TagFolders && len(bm.Tags) > 0 {
+			folder = bm.Tags[0]
+		}
+		byFolder[folder] = append(byFolder[folder], bm)
+	}
+	folders := make([]string, 0, len(byFolder))
+	for folder := range byFolder {
+		folders = append(folders, folder)
+	}
+	sort.Strings(folders)
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString("# Bookmarks\n")
+	for _, folder := range folders {
+		if folder != "" {
+			fmt.Fprintf(bw, "\n## %s\n", folder)
+		}
+		bw.WriteString("\n")
+		for _, bm := range byFolder[folder] {
+			fmt.Fprintf(bw, "- [%s](<%s>)", markdownEscaper.Replace(bm.Title), bm.URL)
+			if bm.Description != "" {
+				fmt.Fprintf(bw, " — %s", markdownEscaper.Replace(strings.Join(strings.Fields(bm.Description), " ")))
+			}
+			for _, tag := range bm.Tags {
+				fmt.Fprintf(bw, " `%s`", tag)
+			}
+			bw.WriteString("\n")
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,86 @@
+package exporter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/abhijith/bookmark-cli/internal/importer"
+	"github.com/abhijith/bookmark-cli/internal/models"
+	"github.com/abhijith/bookmark-cli/internal/store"
+)
+
+// exported are the bookmarks the round-trip tests write and read back
+var exported = []models.Bookmark{
+	{
+		URL:         "https://go.dev/blog/pipelines",
+		Title:       "Go Concurrency Patterns: Pipelines & \"Stages\"",
+		Description: "Stages connected by channels",
+		Tags:        []string{"go", "lang/go/concurrency"},
+		Folder:      "Bookmarks Bar/Dev/Go",
+		Sources:     []string{"chrome", "firefox"},
+		CreatedAt:   1400000000,
+		UpdatedAt:   1700000000,
+	},
+	{
+		URL:       "https://example.com/search?q=pasta&page=2",
+		Title:     "Pasta <fresh>",
+		Folder:    "Recipes",
+		Sources:   []string{"manual"},
+		CreatedAt: 1600000000,
+		UpdatedAt: 1600000100,
+	},
+	{
+		URL:       "https://news.ycombinator.com/",
+		Title:     "Hacker News",
+		Tags:      []string{"news"},
+		Sources:   []string{"safari"},
+		CreatedAt: 1650000000,
+		UpdatedAt: 1650000000,
+	},
+}
+
+// writeFile writes bookmarks to a temporary file with write and returns its path
+func writeFile(t *testing.T, name string, write writer, opts Options) string {
+	path := filepath.Join(t.TempDir(), name)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := write(f, exported, opts); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	path := writeFile(t, "bookmarks.json", writeJSON, Options{})
+
+	src := importer.JSONSource{Path: path}
+	got, err := src.Read(context.Background())
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if !reflect.DeepEqual(got, exported) {
+		t.Errorf("read back\n%+v\nwant\n%+v", got, exported)
+	}
+
+	// Importing keeps the timestamps and sources instead of stamping new ones
+	st := store.NewMemoryStore()
+	if _, err := importer.Import(context.Background(), st, src, importer.Options{Strategy: store.MergeUnion}, nil); err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	stored, err := st.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range stored {
+		stored[i].ID = ""
+	}
+	if !reflect.DeepEqual(stored, exported) {
+		t.Errorf("imported\n%+v\nwant\n%+v", stored, exported)
+	}
+}
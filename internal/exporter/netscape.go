@@ -0,0 +1,101 @@
+package exporter
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/abhijith/bookmark-cli/internal/models"
+)
+
+const netscapeHeader = `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<!-- This is an automatically generated file.
+     It will be read and overwritten.
+     DO NOT EDIT! -->
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks</H1>
+`
+
+// folder is one <DL> of a Netscape bookmark file
+type folder struct {
+	name      string
+	folders   map[string]*folder
+	bookmarks []models.Bookmark
+}
+
+func newFolder(name string) *folder {
+	return &folder{name: name, folders: make(map[string]*folder)}
+}
+
+// add files bm under the folder at path, a "/"-separated list of names
+func (f *folder) add(path string, bm models.Bookmark) {
+	for _, name := range strings.Split(path, "/") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		child, ok := f.folders[name]
+		if !ok {
+			child = newFolder(name)
+			f.folders[name] = child
+		}
+		f = child
+	}
+	f.bookmarks = append(f.bookmarks, bm)
+}
+
+// writeNetscape writes the Netscape bookmark file format that browsers
+// import, with one <H3> folder per path segment and tags in TAGS
+func writeNetscape(w io.Writer, bookmarks []models.Bookmark, opts Options) error {
+	root := newFolder("")
+	for _, bm := range bookmarks {
+		path := bm.Folder
+		if path == "" && opts.TagFolders && len(bm.Tags) > 0 {
+			path = bm.Tags[0]
+		}
+		root.add(path, bm)
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString(netscapeHeader)
+	writeFolder(bw, root, 0)
+	return bw.Flush()
+}
+
+// writeFolder writes the contents of f, subfolders first, in a <DL> list
+func writeFolder(w *bufio.Writer, f *folder, depth int) {
+	indent := strings.Repeat("    ", depth)
+	fmt.Fprintf(w, "%s<DL><p>\n", indent)
+
+	names := make([]string, 0, len(f.folders))
+	for name := range f.folders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "%s    <DT><H3>%s</H3>\n", indent, html.EscapeString(name))
+		writeFolder(w, f.folders[name], depth+1)
+	}
+
+	for _, bm := range f.bookmarks {
+		fmt.Fprintf(w, "%s    <DT><A HREF=\"%s\"", indent, html.EscapeString(bm.URL))
+		if bm.CreatedAt > 0 {
+			fmt.Fprintf(w, " ADD_DATE=\"%d\"", bm.CreatedAt)
+		}
+		if bm.UpdatedAt > 0 {
+			fmt.Fprintf(w, " LAST_MODIFIED=\"%d\"", bm.UpdatedAt)
+		}
+		if len(bm.Tags) > 0 {
+			fmt.Fprintf(w, " TAGS=\"%s\"", html.EscapeString(strings.Join(bm.Tags, ",")))
+		}
+		fmt.Fprintf(w, ">%s</A>\n", html.EscapeString(bm.Title))
+		if bm.Description != "" {
+			fmt.Fprintf(w, "%s    <DD>%s\n", indent, html.EscapeString(bm.Description))
+		}
+	}
+
+	fmt.Fprintf(w, "%s</DL><p>\n", indent)
+}
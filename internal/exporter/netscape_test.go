@@ -0,0 +1,52 @@
+package exporter
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/abhijith/bookmark-cli/internal/browser"
+	"github.com/abhijith/bookmark-cli/internal/models"
+)
+
+func TestNetscapeRoundTrip(t *testing.T) {
+	path := writeFile(t, "bookmarks.html", writeNetscape, Options{})
+
+	got, err := browser.HTMLSource{Path: path}.Read(context.Background())
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+
+	// The file has no sources, and subfolders come before loose bookmarks
+	var want []models.Bookmark
+	for _, bm := range exported {
+		bm.Sources = nil
+		want = append(want, bm)
+	}
+	sort.Slice(got, func(i, j int) bool { return got[i].CreatedAt < got[j].CreatedAt })
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("read back\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestNetscapeTagFolders(t *testing.T) {
+	path := writeFile(t, "bookmarks.html", writeNetscape, Options{TagFolders: true})
+
+	got, err := browser.HTMLSource{Path: path}.Read(context.Background())
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	folders := make(map[string]string)
+	for _, bm := range got {
+		folders[bm.URL] = bm.Folder
+	}
+	want := map[string]string{
+		"https://go.dev/blog/pipelines":             "Bookmarks Bar/Dev/Go",
+		"https://example.com/search?q=pasta&page=2": "Recipes",
+		"https://news.ycombinator.com/":             "news",
+	}
+	if !reflect.DeepEqual(folders, want) {
+		t.Errorf("folders = %v, want %v", folders, want)
+	}
+}
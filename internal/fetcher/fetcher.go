@@ -0,0 +1,144 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	// DefaultTimeout bounds a whole page fetch
+	DefaultTimeout = 10 * time.Second
+	// maxPageBytes is how much of a page is read looking for its metadata
+	maxPageBytes = 1 << 20
+	userAgent    = "bookmark-cli"
+)
+
+// Metadata is what a page says about itself in its <head>
+type Metadata struct {
+	Title       string
+	Description string
+	SiteName    string
+}
+
+// Fetcher downloads pages and extracts their metadata
+type Fetcher struct {
+	Client *http.Client
+}
+
+// New returns a Fetcher using client, or a default client with DefaultTimeout when nil
+func New(client *http.Client) *Fetcher {
+	if client == nil {
+		client = &http.Client{Timeout: DefaultTimeout}
+	}
+	return &Fetcher{Client: client}
+}
+
+// Fetch retrieves url and returns the metadata of the HTML page it serves
+func (f *Fetcher) Fetch(ctx context.Context, url string) (Metadata, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return Metadata{}, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.5")
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("failed to fetch %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return Metadata{}, fmt.Errorf("failed to fetch %s: %s", url, resp.Status)
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil &&
+		mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return Metadata{}, fmt.Errorf("%s is not an HTML page (%s)", url, mediaType)
+	}
+
+	body, err := charset.NewReader(io.LimitReader(resp.Body, maxPageBytes), contentType)
+	if err != nil {
+		return Metadata{}, fmt.Errorf("failed to decode %s: %v", url, err)
+	}
+	return Parse(body)
+}
+
+// Parse reads the <title>, description and OpenGraph tags from an HTML
+// document. OpenGraph values take precedence over the plain ones, which
+// are often padded with the site name.
+func Parse(r io.Reader) (Metadata, error) {
+	var title, description string
+	meta := make(map[string]string)
+
+	z := html.NewTokenizer(r)
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if err := z.Err(); err != io.EOF {
+				return Metadata{}, fmt.Errorf("failed to parse page: %v", err)
+			}
+			return metadata(title, description, meta), nil
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+			switch t.DataAtom {
+			case atom.Title:
+				if title == "" && z.Next() == html.TextToken {
+					title = clean(string(z.Text()))
+				}
+			case atom.Meta:
+				var key, content string
+				for _, a := range t.Attr {
+					switch strings.ToLower(a.Key) {
+					case "name", "property":
+						key = strings.ToLower(a.Val)
+					case "content":
+						content = clean(a.Val)
+					}
+				}
+				if key != "" && content != "" {
+					if _, ok := meta[key]; !ok {
+						meta[key] = content
+					}
+				}
+				if key == "description" && description == "" {
+					description = content
+				}
+			case atom.Body:
+				// Metadata lives in the head; no need to read the page itself
+				return metadata(title, description, meta), nil
+			}
+		}
+	}
+}
+
+func metadata(title, description string, meta map[string]string) Metadata {
+	return Metadata{
+		Title:       first(meta["og:title"], meta["twitter:title"], title),
+		Description: first(meta["og:description"], description, meta["twitter:description"]),
+		SiteName:    meta["og:site_name"],
+	}
+}
+
+func first(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// clean collapses runs of whitespace, as titles often span several lines
+func clean(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,100 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFetch(t *testing.T) {
+	pages := map[string]struct {
+		contentType string
+		status      int
+		body        string
+	}{
+		"/plain": {"text/html; charset=utf-8", http.StatusOK, `<!DOCTYPE html>
+<html><head>
+<title>
+  Plain   page
+</title>
+<meta name="description" content="What the page is about">
+</head><body><title>Not this</title></body></html>`},
+		"/og": {"text/html", http.StatusOK, `<html><head>
+<title>Article | Example Site</title>
+<meta name="description" content="Padded description">
+<meta property="og:title" content="Article">
+<meta property="og:description" content="The real description">
+<meta property="og:site_name" content="Example Site">
+</head></html>`},
+		"/twitter": {"text/html", http.StatusOK, `<html><head>
+<meta name="twitter:title" content="Tweet title">
+<meta name="twitter:description" content="Tweet description">
+</head></html>`},
+		"/latin1":  {"text/html; charset=iso-8859-1", http.StatusOK, "<title>Caf\xe9</title>"},
+		"/missing": {"text/html", http.StatusNotFound, "<title>Not found</title>"},
+		"/error":   {"text/html", http.StatusInternalServerError, ""},
+		"/pdf":     {"application/pdf", http.StatusOK, "%PDF-1.4"},
+		"/json":    {"application/json", http.StatusOK, `{"title": "no"}`},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("User-Agent"); got != userAgent {
+			t.Errorf("User-Agent = %q, want %q", got, userAgent)
+		}
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", page.contentType)
+		w.WriteHeader(page.status)
+		fmt.Fprint(w, page.body)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		path    string
+		want    Metadata
+		wantErr string
+	}{
+		{path: "/plain", want: Metadata{Title: "Plain page", Description: "What the page is about"}},
+		{path: "/og", want: Metadata{Title: "Article", Description: "The real description", SiteName: "Example Site"}},
+		{path: "/twitter", want: Metadata{Title: "Tweet title", Description: "Tweet description"}},
+		{path: "/latin1", want: Metadata{Title: "Café"}},
+		{path: "/missing", wantErr: "404"},
+		{path: "/error", wantErr: "500"},
+		{path: "/pdf", wantErr: "not an HTML page"},
+		{path: "/json", wantErr: "not an HTML page"},
+	}
+
+	f := New(server.Client())
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := f.Fetch(context.Background(), server.URL+tt.path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Fetch error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Fetch: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Fetch = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	if _, err := New(nil).Fetch(context.Background(), url); err == nil {
+		t.Fatal("Fetch of a closed server succeeded")
+	}
+}
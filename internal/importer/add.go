@@ -0,0 +1,127 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/abhijith/bookmark-cli/internal/fetcher"
+	"github.com/abhijith/bookmark-cli/internal/models"
+	"github.com/abhijith/bookmark-cli/internal/store"
+	"github.com/abhijith/bookmark-cli/internal/urlnorm"
+	"github.com/urfave/cli/v2"
+)
+
+// AddCommand bookmarks a single URL, filling in the title and description
+// from the page unless they are given. A URL that is already bookmarked has
+// the new fields merged in with the strategy named by BOOKMARK_MERGE.
+func AddCommand(st store.Store) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		if c.NArg() < 1 {
+			return cli.Exit("Missing URL argument", 1)
+		}
+
+		f := fetcher.New(&http.Client{Timeout: c.Duration("timeout")})
+		bm, result, err := AddBookmark(context.Background(), st, f, c.Args().First(), AddOptions{
+			Title:       c.String("title"),
+			Description: c.String("desc"),
+			Tags:        c.StringSlice("tag"),
+			NoFetch:     c.Bool("no-fetch"),
+			Strategy:    store.DefaultMergeStrategy(),
+		})
+		if err != nil {
+			return cli.Exit(err.Error(), 1)
+		}
+
+		switch result {
+		case store.Added:
+			fmt.Printf("Added %s\n", store.ShortID(bm.ID))
+		case store.Merged:
+			fmt.Printf("Merged into existing bookmark %s\n", store.ShortID(bm.ID))
+		default:
+			fmt.Printf("Skipped: already bookmarked as %s with nothing new to add\n", store.ShortID(bm.ID))
+		}
+		fmt.Printf("  %s\n", bm.Title)
+		fmt.Printf("  %s\n", bm.URL)
+		if len(bm.Tags) > 0 {
+			fmt.Printf("  Tags: %s\n", strings.Join(bm.Tags, ", "))
+		}
+		return nil
+	}
+}
+
+// AddOptions are the fields given on the command line; empty ones are
+// filled in from the page
+type AddOptions struct {
+	Title       string
+	Description string
+	Tags        []string
+	NoFetch     bool
+	// Strategy merges the bookmark into the stored one when the URL is
+	// already bookmarked
+	Strategy store.MergeStrategy
+}
+
+// AddBookmark normalises rawURL, fetches missing metadata with f and stores
+// the bookmark through store.Upsert, as imports do. It returns the bookmark
+// as stored and what Upsert did. A page that cannot be fetched is still
+// bookmarked, with a warning on stderr.
+func AddBookmark(ctx context.Context, st store.Store, f *fetcher.Fetcher, rawURL string, opts AddOptions) (models.Bookmark, store.UpsertResult, error) {
+	u, err := urlnorm.Clean(rawURL)
+	if err != nil {
+		return models.Bookmark{}, store.Unchanged, err
+	}
+
+	now := time.Now().Unix()
+	bm := models.Bookmark{
+		ID:          store.NewID(u),
+		URL:         u,
+		Title:       strings.TrimSpace(opts.Title),
+		Description: strings.TrimSpace(opts.Description),
+		Sources:     []string{"manual"},
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	for _, tag := range opts.Tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			bm.Tags = append(bm.Tags, tag)
+		}
+	}
+
+	// A known page already has its metadata, so only new pages are fetched
+	exists, err := st.HasURL(ctx, bm.URL)
+	if err != nil {
+		return bm, store.Unchanged, err
+	}
+	if !exists && !opts.NoFetch && (bm.Title == "" || bm.Description == "") {
+		meta, err := f.Fetch(ctx, bm.URL)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not read page metadata: %v\n", err)
+		}
+		if bm.Title == "" {
+			bm.Title = meta.Title
+		}
+		if bm.Title == "" {
+			bm.Title = meta.SiteName
+		}
+		if bm.Description == "" {
+			bm.Description = meta.Description
+		}
+	}
+	if bm.Title == "" {
+		bm.Title = bm.URL
+	}
+
+	result, err := store.Upsert(ctx, st, bm, opts.Strategy)
+	if err != nil || result == store.Added {
+		return bm, result, err
+	}
+	// Show the stored bookmark with everything merged into it
+	if stored, err := st.Get(ctx, bm.ID); err == nil {
+		bm = stored
+	}
+	return bm, result, nil
+}
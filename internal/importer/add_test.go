@@ -0,0 +1,52 @@
+package importer
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/abhijith/bookmark-cli/internal/fetcher"
+	"github.com/abhijith/bookmark-cli/internal/store"
+)
+
+func TestAddBookmarkMergesKnownURL(t *testing.T) {
+	ctx := context.Background()
+	st := store.NewMemoryStore()
+	f := fetcher.New(nil)
+
+	first, result, err := AddBookmark(ctx, st, f, "example.com/page", AddOptions{
+		Title:    "Example",
+		Tags:     []string{"one"},
+		NoFetch:  true,
+		Strategy: store.MergeUnion,
+	})
+	if err != nil || result != store.Added {
+		t.Fatalf("first add = %v, %v; want Added", result, err)
+	}
+
+	bm, result, err := AddBookmark(ctx, st, f, "https://EXAMPLE.com/page", AddOptions{
+		Description: "A page",
+		Tags:        []string{"two"},
+		Strategy:    store.MergeUnion,
+	})
+	if err != nil || result != store.Merged {
+		t.Fatalf("second add = %v, %v; want Merged", result, err)
+	}
+	if bm.ID != first.ID || bm.Title != "Example" || bm.Description != "A page" {
+		t.Errorf("merged bookmark = %+v", bm)
+	}
+	if want := []string{"one", "two"}; !reflect.DeepEqual(bm.Tags, want) {
+		t.Errorf("tags = %v, want %v", bm.Tags, want)
+	}
+
+	_, result, err = AddBookmark(ctx, st, f, "example.com/page", AddOptions{
+		Tags:     []string{"three"},
+		Strategy: store.MergeSkip,
+	})
+	if err != nil || result != store.Unchanged {
+		t.Fatalf("add with MergeSkip = %v, %v; want Unchanged", result, err)
+	}
+	if n, _ := st.Count(ctx); n != 1 {
+		t.Errorf("store holds %d bookmarks, want 1", n)
+	}
+}
@@ -2,143 +2,122 @@ package importer
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"hash/fnv"
 	"io/ioutil"
-	"strconv"
-	"strings"
-	"time"
 
 	"github.com/abhijith/bookmark-cli/internal/models"
-	"github.com/go-redis/redis/v8"
-	"github.com/schollz/progressbar/v3"
+	"github.com/abhijith/bookmark-cli/internal/store"
 	"github.com/tidwall/gjson"
 	"github.com/urfave/cli/v2"
 )
 
-const (
-	RedisBookmarksKey = "bookmarks:index"
-	RedisURLSetKey    = "bookmarks:urls"
-	RedisTitleSetKey  = "bookmarks:titles"
-)
-
-func ImportCommand(redisClient *redis.Client) cli.ActionFunc {
+// ImportCommand imports a JSON file of bookmarks
+func ImportCommand(st store.Store) cli.ActionFunc {
 	return func(c *cli.Context) error {
 		if c.NArg() < 1 {
 			return cli.Exit("Missing file argument", 1)
 		}
 
-		filePath := c.Args().Get(0)
-		return ImportBookmarks(redisClient, filePath)
+		_, err := Run(st, JSONSource{Path: c.Args().Get(0)}, OptionsFromFlags(c))
+		return err
 	}
 }
 
-func CleanCommand(redisClient *redis.Client) cli.ActionFunc {
-	return func(c *cli.Context) error {
-		return CleanDuplicates(redisClient)
-	}
+// OptionsFromFlags returns the default options with the command's --dry-run
+func OptionsFromFlags(c *cli.Context) Options {
+	opts := DefaultOptions()
+	opts.DryRun = c.Bool("dry-run")
+	return opts
 }
 
-func ImportBookmarks(redisClient *redis.Client, filePath string) error {
-	data, err := ioutil.ReadFile(filePath)
-	if err != nil {
-		return err
+func CleanCommand(st store.Store) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		return CleanDuplicates(st, c.Bool("dry-run"))
 	}
+}
 
-	bookmarks := gjson.GetBytes(data, "bookmarks").Array()
-	if len(bookmarks) == 0 {
-		return fmt.Errorf("no bookmarks found in file")
-	}
+// JSONSource reads a file with a top-level "bookmarks" array, as written by
+// `bm export json`
+type JSONSource struct {
+	Path string
+}
 
-	bar := progressbar.Default(int64(len(bookmarks)), "Importing")
-	ctx := context.Background()
+func (s JSONSource) Name() string {
+	return "json"
+}
 
-	imported := 0
-	skipped := 0
+func (s JSONSource) Read(ctx context.Context) ([]models.Bookmark, error) {
+	data, err := ioutil.ReadFile(s.Path)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, item := range bookmarks {
+	var bookmarks []models.Bookmark
+	for _, item := range gjson.GetBytes(data, "bookmarks").Array() {
 		bm := models.Bookmark{
 			URL:         item.Get("url").String(),
 			Title:       item.Get("title").String(),
 			Description: item.Get("description").String(),
+			Folder:      item.Get("folder").String(),
 			CreatedAt:   item.Get("created_at").Int(),
-			UpdatedAt:   time.Now().Unix(),
+			UpdatedAt:   item.Get("updated_at").Int(),
 		}
-
-		// Generate unique ID
-		bm.ID = generateID(bm.URL)
-
-		// Parse tags
-		tags := item.Get("tags").Array()
-		for _, tag := range tags {
+		for _, tag := range item.Get("tags").Array() {
 			bm.Tags = append(bm.Tags, tag.String())
 		}
-
-		// Deduplicate using Redis set
-		exists, err := redisClient.SAdd(ctx, RedisURLSetKey, bm.URL).Result()
-		if err != nil {
-			return err
-		}
-		if exists == 0 {
-			skipped++
-			bar.Add(1)
-			continue // Skip duplicates
-		}
-
-		// Add to search index
-		jsonData, _ := json.Marshal(bm)
-		if err := redisClient.ZAdd(ctx, RedisBookmarksKey, &redis.Z{
-			Score:  float64(bm.CreatedAt),
-			Member: jsonData,
-		}).Err(); err != nil {
-			return err
-		}
-
-		// Index title terms
-		terms := strings.Fields(strings.ToLower(bm.Title))
-		for _, term := range terms {
-			redisClient.SAdd(ctx, RedisTitleSetKey, term)
+		for _, source := range item.Get("sources").Array() {
+			bm.Sources = append(bm.Sources, source.String())
 		}
-
-		imported++
-		bar.Add(1)
+		bookmarks = append(bookmarks, bm)
 	}
-
-	bar.Finish()
-	fmt.Printf("Import complete: %d imported, %d skipped\n", imported, skipped)
-	return nil
+	return bookmarks, nil
 }
 
-func CleanDuplicates(redisClient *redis.Client) error {
+// CleanDuplicates merges bookmarks that share a canonical URL and repairs
+// the indexes, printing what it found. With dryRun nothing is changed.
+func CleanDuplicates(st store.Store, dryRun bool) error {
 	ctx := context.Background()
-
-	// Get all URLs and remove duplicates
-	urls, err := redisClient.SMembers(ctx, RedisURLSetKey).Result()
+	before, err := st.Count(ctx)
 	if err != nil {
 		return err
 	}
 
-	fmt.Printf("Found %d unique URLs\n", len(urls))
-
-	// Clear the URL set and rebuild
-	redisClient.Del(ctx, RedisURLSetKey)
+	report, err := st.Clean(ctx, store.CleanOptions{Strategy: store.DefaultMergeStrategy(), DryRun: dryRun})
+	if err != nil {
+		return fmt.Errorf("cleanup failed: %v", err)
+	}
 
-	// Rebuild URL set with unique URLs
-	for _, url := range urls {
-		redisClient.SAdd(ctx, RedisURLSetKey, url)
+	verb := "Fixed"
+	if dryRun {
+		verb = "Found"
+	}
+	fmt.Printf("%-42s %d\n", "Bookmarks before:", before)
+	for _, row := range []struct {
+		label string
+		count int
+	}{
+		{"duplicates", report.Duplicates},
+		{"index entries without a bookmark", report.MissingBookmarks},
+		{"bookmarks missing from the index", report.Unindexed},
+		{"stale URLs", report.StaleURLs},
+		{"missing URLs", report.MissingURLs},
+		{"orphaned term entries", report.OrphanTerms},
+		{"orphaned tag entries", report.OrphanTags},
+	} {
+		fmt.Printf("  %-40s %d\n", verb+" "+row.label+":", row.count)
 	}
 
-	fmt.Println("Duplicate cleanup complete")
+	if dryRun {
+		after := before - report.MissingBookmarks + report.Unindexed - report.Duplicates
+		fmt.Printf("%-42s %d (projected)\n", "Bookmarks after:", after)
+		fmt.Println("Dry run: nothing was changed")
+		return nil
+	}
+	after, err := st.Count(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%-42s %d\n", "Bookmarks after:", after)
+	fmt.Println("Cleanup complete")
 	return nil
 }
-
-func generateID(url string) string {
-	// Simple ID generation - replace with proper UUID in production
-	h := fnv.New64a()
-	h.Write([]byte(url))
-	return strconv.FormatUint(h.Sum64(), 16)
-}
-
-
-
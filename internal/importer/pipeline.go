@@ -0,0 +1,191 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/abhijith/bookmark-cli/internal/models"
+	"github.com/abhijith/bookmark-cli/internal/store"
+	"github.com/abhijith/bookmark-cli/internal/urlnorm"
+	"github.com/schollz/progressbar/v3"
+)
+
+// Source reads bookmarks from one place, such as an export file or a
+// browser profile. Everything after reading is done by Import.
+type Source interface {
+	// Name is a short lowercase label, recorded in each bookmark's Sources
+	Name() string
+	// Read returns the bookmarks found, as they are in the source
+	Read(ctx context.Context) ([]models.Bookmark, error)
+}
+
+// Options controls an import
+type Options struct {
+	Strategy store.MergeStrategy
+	// DryRun counts what would be imported without writing anything
+	DryRun bool
+}
+
+// DefaultOptions merges with the strategy named by BOOKMARK_MERGE
+func DefaultOptions() Options {
+	return Options{Strategy: store.DefaultMergeStrategy()}
+}
+
+// Stats counts what an import did, or would do on a dry run
+type Stats struct {
+	Read     int
+	Imported int
+	Merged   int
+	Skipped  int // already stored with nothing new to add
+	Invalid  int // not an http or https URL
+}
+
+// Import reads src and passes each bookmark through the pipeline: normalise,
+// enrich, then merge into the bookmark already stored for its URL or add it.
+// progress may be nil.
+func Import(ctx context.Context, st store.Store, src Source, opts Options, progress func(done, total int)) (Stats, error) {
+	var stats Stats
+	bookmarks, err := src.Read(ctx)
+	if err != nil {
+		return stats, err
+	}
+	stats.Read = len(bookmarks)
+
+	// A dry run keeps what it would have written so that duplicates within
+	// the source are counted as merges, as they are on a real run
+	pending := make(map[string]models.Bookmark)
+	now := time.Now().Unix()
+	for i, bm := range bookmarks {
+		bm, ok := normalise(bm)
+		if ok {
+			enrich(&bm, src.Name(), now)
+			var result store.UpsertResult
+			if opts.DryRun {
+				result, err = plan(ctx, st, bm, opts.Strategy, pending)
+			} else {
+				result, err = store.Upsert(ctx, st, bm, opts.Strategy)
+			}
+			if err != nil {
+				return stats, fmt.Errorf("failed to import %s: %v", bm.URL, err)
+			}
+			switch result {
+			case store.Added:
+				stats.Imported++
+			case store.Merged:
+				stats.Merged++
+			default:
+				stats.Skipped++
+			}
+		} else {
+			stats.Invalid++
+		}
+		if progress != nil {
+			progress(i+1, len(bookmarks))
+		}
+	}
+	return stats, nil
+}
+
+// Run imports src with a progress bar and prints the stats
+func Run(st store.Store, src Source, opts Options) (Stats, error) {
+	var bar *progressbar.ProgressBar
+	stats, err := Import(context.Background(), st, src, opts, func(done, total int) {
+		if bar == nil {
+			bar = progressbar.Default(int64(total), fmt.Sprintf("Importing from %s", src.Name()))
+		}
+		bar.Set(done)
+	})
+	if bar != nil {
+		bar.Finish()
+	}
+	if err != nil {
+		return stats, err
+	}
+	if stats.Read == 0 {
+		return stats, fmt.Errorf("no bookmarks found in %s", src.Name())
+	}
+
+	if opts.DryRun {
+		fmt.Printf("Dry run of %s import: %d would be imported, %d merged, %d skipped", src.Name(), stats.Imported, stats.Merged, stats.Skipped)
+	} else {
+		fmt.Printf("%s import complete: %d imported, %d merged, %d skipped", src.Name(), stats.Imported, stats.Merged, stats.Skipped)
+	}
+	if stats.Invalid > 0 {
+		fmt.Printf(", %d invalid", stats.Invalid)
+	}
+	fmt.Println()
+	return stats, nil
+}
+
+// normalise trims every field and cleans the URL, reporting false for
+// bookmarks that are not web pages, such as javascript: or place: URLs
+func normalise(bm models.Bookmark) (models.Bookmark, bool) {
+	raw := strings.TrimSpace(bm.URL)
+	lower := strings.ToLower(raw)
+	if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") {
+		return bm, false
+	}
+	u, err := urlnorm.Clean(raw)
+	if err != nil {
+		return bm, false
+	}
+
+	bm.URL = u
+	bm.Title = strings.Join(strings.Fields(bm.Title), " ")
+	bm.Description = strings.TrimSpace(bm.Description)
+	bm.Folder = strings.Trim(strings.TrimSpace(bm.Folder), "/")
+	var tags []string
+	for _, tag := range bm.Tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	bm.Tags = tags
+	return bm, true
+}
+
+// enrich fills in what the source left out and records where the bookmark
+// came from, unless the source already says so, as exports do
+func enrich(bm *models.Bookmark, source string, now int64) {
+	bm.ID = store.NewID(bm.URL)
+	if bm.Title == "" {
+		bm.Title = bm.URL
+	}
+	if bm.CreatedAt <= 0 {
+		bm.CreatedAt = now
+	}
+	if bm.UpdatedAt <= 0 {
+		bm.UpdatedAt = now
+	}
+	if len(bm.Sources) == 0 {
+		bm.Sources = []string{source}
+	}
+}
+
+// plan works out what store.Upsert would do with bm without writing it
+func plan(ctx context.Context, st store.Store, bm models.Bookmark, strategy store.MergeStrategy, pending map[string]models.Bookmark) (store.UpsertResult, error) {
+	stored, ok := pending[bm.ID]
+	if !ok {
+		var err error
+		stored, err = st.Get(ctx, bm.ID)
+		if err == store.ErrNotFound {
+			stored, err = store.FindByURL(ctx, st, bm.URL)
+			if err == store.ErrNotFound {
+				pending[bm.ID] = bm
+				return store.Added, nil
+			}
+		}
+		if err != nil {
+			return store.Unchanged, err
+		}
+	}
+
+	merged, changed := store.Merge(stored, bm, strategy)
+	pending[bm.ID] = merged
+	if !changed {
+		return store.Unchanged, nil
+	}
+	return store.Merged, nil
+}
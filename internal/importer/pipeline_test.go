@@ -0,0 +1,48 @@
+package importer
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/abhijith/bookmark-cli/internal/models"
+	"github.com/abhijith/bookmark-cli/internal/store"
+)
+
+type sliceSource []models.Bookmark
+
+func (s sliceSource) Name() string { return "test" }
+
+func (s sliceSource) Read(ctx context.Context) ([]models.Bookmark, error) {
+	return append([]models.Bookmark(nil), s...), nil
+}
+
+func TestImportMergesIntoLegacyID(t *testing.T) {
+	ctx := context.Background()
+	st := store.NewMemoryStore()
+	legacy := models.Bookmark{ID: "legacy", URL: "http://www.example.com/page", Title: "Page", Tags: []string{"old"}, CreatedAt: 1}
+	if err := st.Put(ctx, legacy); err != nil {
+		t.Fatal(err)
+	}
+	src := sliceSource{{URL: "https://example.com/page", Tags: []string{"new"}}}
+
+	stats, err := Import(ctx, st, src, Options{Strategy: store.MergeUnion, DryRun: true}, nil)
+	if err != nil || stats.Merged != 1 {
+		t.Fatalf("dry run = %+v, %v; want 1 merged", stats, err)
+	}
+
+	stats, err = Import(ctx, st, src, Options{Strategy: store.MergeUnion}, nil)
+	if err != nil || stats.Merged != 1 {
+		t.Fatalf("Import = %+v, %v; want 1 merged", stats, err)
+	}
+	if n, _ := st.Count(ctx); n != 1 {
+		t.Errorf("store holds %d bookmarks, want 1", n)
+	}
+	bm, err := st.Get(ctx, "legacy")
+	if err != nil {
+		t.Fatalf("legacy bookmark: %v", err)
+	}
+	if want := []string{"old", "new"}; !reflect.DeepEqual(bm.Tags, want) {
+		t.Errorf("tags = %v, want %v", bm.Tags, want)
+	}
+}
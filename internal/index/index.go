@@ -0,0 +1,138 @@
+package index
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/abhijith/bookmark-cli/internal/models"
+)
+
+// MinTermLength is the shortest token, in runes, that is indexed
+const MinTermLength = 2
+
+// urlNoise are URL tokens that appear on nearly every bookmark
+var urlNoise = map[string]bool{
+	"http":  true,
+	"https": true,
+	"www":   true,
+}
+
+// Tokenize splits text into lowercase letter/digit runs
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// URLTokens tokenizes the host and path of a URL, leaving out the scheme and www
+func URLTokens(rawURL string) []string {
+	text := rawURL
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		text = u.Host + " " + u.Path
+	}
+
+	var tokens []string
+	for _, token := range Tokenize(text) {
+		if !urlNoise[token] {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+// Terms returns the sorted, de-duplicated index terms of a bookmark: tokens of
+// its title, description, URL and tags. Single characters are not indexed.
+func Terms(bm models.Bookmark) []string {
+	seen := make(map[string]bool)
+	add := func(tokens []string) {
+		for _, token := range tokens {
+			if len([]rune(token)) >= MinTermLength {
+				seen[token] = true
+			}
+		}
+	}
+
+	add(Tokenize(bm.Title))
+	add(Tokenize(bm.Description))
+	add(URLTokens(bm.URL))
+	for _, tag := range bm.Tags {
+		add(Tokenize(tag))
+	}
+
+	terms := make([]string, 0, len(seen))
+	for term := range seen {
+		terms = append(terms, term)
+	}
+	sort.Strings(terms)
+	return terms
+}
+
+// Diff returns the terms only in old (to remove) and only in new (to add)
+func Diff(old, new []string) (removed, added []string) {
+	oldSet := make(map[string]bool, len(old))
+	for _, term := range old {
+		oldSet[term] = true
+	}
+	newSet := make(map[string]bool, len(new))
+	for _, term := range new {
+		newSet[term] = true
+		if !oldSet[term] {
+			added = append(added, term)
+		}
+	}
+	for _, term := range old {
+		if !newSet[term] {
+			removed = append(removed, term)
+		}
+	}
+	return removed, added
+}
+
+// Postings is an in-memory term → bookmark ID inverted index
+type Postings map[string]map[string]bool
+
+// Add records that the bookmark id contains terms
+func (p Postings) Add(id string, terms []string) {
+	for _, term := range terms {
+		if p[term] == nil {
+			p[term] = make(map[string]bool)
+		}
+		p[term][id] = true
+	}
+}
+
+// Remove drops id from the postings of terms, forgetting emptied terms
+func (p Postings) Remove(id string, terms []string) {
+	for _, term := range terms {
+		delete(p[term], id)
+		if len(p[term]) == 0 {
+			delete(p, term)
+		}
+	}
+}
+
+// Lookup returns the IDs containing every token, each token matching any
+// indexed term it is a prefix of
+func (p Postings) Lookup(tokens []string) map[string]bool {
+	var result map[string]bool
+	for _, token := range tokens {
+		matches := make(map[string]bool)
+		for term, ids := range p {
+			if !strings.HasPrefix(term, token) {
+				continue
+			}
+			for id := range ids {
+				if result == nil || result[id] {
+					matches[id] = true
+				}
+			}
+		}
+		result = matches
+		if len(result) == 0 {
+			break
+		}
+	}
+	return result
+}
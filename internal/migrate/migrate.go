@@ -0,0 +1,125 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/abhijith/bookmark-cli/internal/store"
+	"github.com/schollz/progressbar/v3"
+	"github.com/urfave/cli/v2"
+)
+
+func MigrateCommand(st store.Store) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		return Migrate(st, c.Bool("dry-run"))
+	}
+}
+
+// CurrentVersion reads the schema version of st; unversioned data is version 0
+func CurrentVersion(ctx context.Context, st store.Store) (int, error) {
+	value, err := st.Meta(ctx, store.SchemaVersionMetaKey)
+	if err != nil {
+		return 0, err
+	}
+	if value == "" {
+		return 0, nil
+	}
+
+	version, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid schema version %q", value)
+	}
+	return version, nil
+}
+
+// Check refuses to work on data written by a newer binary or on data that
+// still needs `migrate`. An empty store is stamped with the latest version.
+func Check(st store.Store) error {
+	ctx := context.Background()
+	version, err := CurrentVersion(ctx, st)
+	if err != nil {
+		return err
+	}
+
+	latest := LatestVersion()
+	switch {
+	case version > latest:
+		return fmt.Errorf("database schema version %d is newer than this binary supports (%d); please upgrade bm", version, latest)
+	case version < latest:
+		count, err := st.Count(ctx)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return setVersion(ctx, st, latest)
+		}
+		return fmt.Errorf("database schema version %d is out of date (latest %d); run `bm migrate` first", version, latest)
+	}
+	return nil
+}
+
+// Migrate applies every pending migration in order, recording the version
+// after each step so an interrupted run resumes where it stopped
+func Migrate(st store.Store, dryRun bool) error {
+	ctx := context.Background()
+	version, err := CurrentVersion(ctx, st)
+	if err != nil {
+		return err
+	}
+
+	latest := LatestVersion()
+	if version > latest {
+		return fmt.Errorf("database schema version %d is newer than this binary supports (%d); please upgrade bm", version, latest)
+	}
+	if version == latest {
+		fmt.Printf("Schema is up to date (version %d)\n", version)
+		return nil
+	}
+
+	fmt.Printf("Schema version %d, latest %d\n", version, latest)
+	for _, m := range migrations {
+		if m.Version <= version {
+			continue
+		}
+
+		pending, err := m.Plan(ctx, st)
+		if err != nil {
+			return fmt.Errorf("migration %d: %v", m.Version, err)
+		}
+
+		if dryRun {
+			fmt.Printf("  v%d: %s (%d records)\n", m.Version, m.Description, pending)
+			continue
+		}
+
+		fmt.Printf("Applying v%d: %s\n", m.Version, m.Description)
+		changed := 0
+		if pending > 0 {
+			bar := progressbar.Default(int64(pending), fmt.Sprintf("Migrating to v%d", m.Version))
+			changed, err = m.Apply(ctx, st, func(done, total int) {
+				bar.Set(done)
+			})
+			bar.Finish()
+			if err != nil {
+				return fmt.Errorf("migration %d stopped after %d records: %v", m.Version, changed, err)
+			}
+		}
+
+		if err := setVersion(ctx, st, m.Version); err != nil {
+			return err
+		}
+		fmt.Printf("v%d complete: %d records changed\n", m.Version, changed)
+	}
+
+	if dryRun {
+		fmt.Println("Dry run: nothing was changed")
+		return nil
+	}
+	fmt.Printf("Migration complete: schema version %d\n", latest)
+	return nil
+}
+
+func setVersion(ctx context.Context, st store.Store, version int) error {
+	return st.SetMeta(ctx, store.SchemaVersionMetaKey, strconv.Itoa(version))
+}
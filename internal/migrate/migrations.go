@@ -0,0 +1,101 @@
+package migrate
+
+import (
+	"context"
+
+	"github.com/abhijith/bookmark-cli/internal/store"
+)
+
+// Migration upgrades a store from Version-1 to Version
+type Migration struct {
+	Version     int
+	Description string
+	// Plan returns how many records Apply would touch, without changing anything
+	Plan func(ctx context.Context, st store.Store) (int, error)
+	// Apply performs the upgrade and returns how many records it changed
+	Apply func(ctx context.Context, st store.Store, progress func(done, total int)) (int, error)
+}
+
+// migrations is the ordered registry; append new steps at the end and never
+// renumber released ones.
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "Store bookmarks as bookmark:<id> hashes instead of JSON index members",
+		Plan: func(ctx context.Context, st store.Store) (int, error) {
+			rs, ok := st.(*store.RedisStore)
+			if !ok {
+				return 0, nil
+			}
+			return rs.CountLegacy(ctx)
+		},
+		Apply: func(ctx context.Context, st store.Store, progress func(done, total int)) (int, error) {
+			rs, ok := st.(*store.RedisStore)
+			if !ok {
+				return 0, nil
+			}
+			return rs.MigrateToHashes(ctx, progress)
+		},
+	},
+	{
+		Version:     2,
+		Description: "Build the term index used by search",
+		Plan: func(ctx context.Context, st store.Store) (int, error) {
+			return st.Count(ctx)
+		},
+		Apply: func(ctx context.Context, st store.Store, progress func(done, total int)) (int, error) {
+			return st.Reindex(ctx, progress)
+		},
+	},
+	{
+		Version:     3,
+		Description: "Build the tag index used by tag filters and `bm tags`",
+		Plan: func(ctx context.Context, st store.Store) (int, error) {
+			return st.Count(ctx)
+		},
+		Apply: func(ctx context.Context, st store.Store, progress func(done, total int)) (int, error) {
+			return st.Reindex(ctx, progress)
+		},
+	},
+	{
+		Version:     4,
+		Description: "Key bookmarks by canonical URL, merging variants of the same page",
+		Plan: func(ctx context.Context, st store.Store) (int, error) {
+			return st.Count(ctx)
+		},
+		Apply: rekey,
+	},
+	{
+		Version:     5,
+		Description: "Pad bookmark IDs to 16 hex digits",
+		Plan: func(ctx context.Context, st store.Store) (int, error) {
+			bookmarks, err := st.List(ctx)
+			if err != nil {
+				return 0, err
+			}
+			changed, _, err := store.PlanRekey(bookmarks)
+			if err != nil || changed == 0 {
+				return 0, err
+			}
+			// Rekey rewrites every record, not only the changed ones
+			return len(bookmarks), nil
+		},
+		Apply: rekey,
+	},
+}
+
+// rekey runs Store.Rekey and returns how many records it rewrote rather than
+// how many it merged, which is what Apply reports
+func rekey(ctx context.Context, st store.Store, progress func(done, total int)) (int, error) {
+	rewritten := 0
+	_, err := st.Rekey(ctx, func(done, total int) {
+		rewritten = done
+		progress(done, total)
+	})
+	return rewritten, err
+}
+
+// LatestVersion is the schema version this binary reads and writes
+func LatestVersion() int {
+	return migrations[len(migrations)-1].Version
+}
@@ -5,10 +5,9 @@ type Bookmark struct {
 	Title       string   `json:"title" redis:"title"`
 	Description string   `json:"description" redis:"description"`
 	Tags        []string `json:"tags" redis:"tags"`
+	Folder      string   `json:"folder" redis:"folder"`
+	Sources     []string `json:"sources,omitempty" redis:"sources"`
 	CreatedAt   int64    `json:"created_at" redis:"created_at"`
 	UpdatedAt   int64    `json:"updated_at" redis:"updated_at"`
 	ID          string   `json:"id" redis:"id"`
 }
-
-
-
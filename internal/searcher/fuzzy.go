@@ -0,0 +1,245 @@
+package searcher
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/abhijith/bookmark-cli/internal/store"
+)
+
+const (
+	// fuzzyPenalty scales the relevance of a fuzzy hit once per edit
+	fuzzyPenalty = 0.5
+	// maxFuzzyTerms caps how many close terms stand in for one typo
+	maxFuzzyTerms = 5
+)
+
+// fuzzyTerm is an index term or tag within a few edits of a query token
+type fuzzyTerm struct {
+	term string
+	dist int
+}
+
+// maxEdits is the edit budget for a token: none for short words, where
+// almost everything is one edit away, up to two for long ones
+func maxEdits(token string) int {
+	switch n := len([]rune(token)); {
+	case n <= 3:
+		return 0
+	case n <= 5:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// editDistance is the optimal string alignment distance: insertions,
+// deletions, substitutions and transpositions of neighbours each cost one
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(rb)]
+}
+
+// closest returns up to limit candidates within the edit budget of token,
+// nearest first. A candidate may also match on its leading part, so a typo
+// in a prefix ("kuberne") still finds the full term.
+func closest(token string, candidates []string, limit int) []fuzzyTerm {
+	budget := maxEdits(token)
+	if budget == 0 {
+		return nil
+	}
+
+	n := len([]rune(token))
+	var matches []fuzzyTerm
+	for _, candidate := range candidates {
+		dist := editDistance(token, candidate)
+		if r := []rune(candidate); len(r) > n+budget {
+			dist = min(dist, editDistance(token, string(r[:n])))
+		}
+		if dist > 0 && dist <= budget {
+			matches = append(matches, fuzzyTerm{candidate, dist})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].dist != matches[j].dist {
+			return matches[i].dist < matches[j].dist
+		}
+		return matches[i].term < matches[j].term
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// expandFuzzy resolves query words that match nothing: two words that are
+// one indexed term when joined ("postgre sql") may match as that term, a
+// misspelled word is widened to the closest index terms, and an unknown tag
+// to the closest existing tags. It returns the rewritten tree and notes
+// describing each substitution.
+func expandFuzzy(ctx context.Context, st store.Store, node Node) (Node, []string, error) {
+	var notes []string
+	var tags map[string]string
+
+	rewritten, err := rewrite(node, func(n textNode) (textNode, error) {
+		switch n.field {
+		case "", "title", "desc":
+			if n.phrase || len(n.tokens) == 0 {
+				return n, nil
+			}
+			return fuzzyText(ctx, st, n, &notes)
+		case "tag":
+			if tags == nil {
+				counts, err := st.Tags(ctx)
+				if err != nil {
+					return n, err
+				}
+				tags = make(map[string]string, len(counts))
+				for tag := range counts {
+					// Parents of nested tags exist as filters even if never used alone
+					for i, r := range tag {
+						if r == '/' {
+							tags[strings.ToLower(tag[:i])] = tag[:i]
+						}
+					}
+					tags[strings.ToLower(tag)] = tag
+				}
+			}
+			return fuzzyTag(n, tags, &notes), nil
+		}
+		return n, nil
+	})
+	return rewritten, notes, err
+}
+
+// fuzzyText fills in the joins and fuzzy alternatives of a text node
+func fuzzyText(ctx context.Context, st store.Store, n textNode, notes *[]string) (textNode, error) {
+	lookups := append([]string(nil), n.tokens...)
+	for i := 0; i+1 < len(n.tokens); i++ {
+		lookups = append(lookups, n.tokens[i]+n.tokens[i+1])
+	}
+	docFreq, err := st.DocFreq(ctx, lookups)
+	if err != nil {
+		return n, err
+	}
+
+	joined := make(map[int]bool)
+	for i := 0; i+1 < len(n.tokens); i++ {
+		if docFreq[n.tokens[i]+n.tokens[i+1]] > 0 {
+			if n.joins == nil {
+				n.joins = make(map[int]string)
+			}
+			n.joins[i] = n.tokens[i] + n.tokens[i+1]
+			joined[i], joined[i+1] = true, true
+		}
+	}
+
+	for i, token := range n.tokens {
+		if docFreq[token] > 0 || joined[i] {
+			continue
+		}
+
+		// Typos rarely hit the first letter, so only terms sharing it are compared
+		vocabulary, err := st.Vocabulary(ctx, string([]rune(token)[:1]))
+		if err != nil {
+			return n, err
+		}
+		alternatives := closest(token, vocabulary, maxFuzzyTerms)
+		if len(alternatives) == 0 {
+			continue
+		}
+
+		if n.fuzzy == nil {
+			n.fuzzy = make(map[string][]fuzzyTerm)
+		}
+		n.fuzzy[token] = alternatives
+		*notes = append(*notes, fmt.Sprintf("%q matched nothing, also searching %s", token, joinTerms(alternatives)))
+	}
+	return n, nil
+}
+
+// fuzzyTag lets an unknown tag filter match the closest existing tags
+func fuzzyTag(n textNode, tags map[string]string, notes *[]string) textNode {
+	value := strings.ToLower(n.value)
+	if _, ok := tags[value]; ok {
+		return n
+	}
+
+	names := make([]string, 0, len(tags))
+	for name := range tags {
+		names = append(names, name)
+	}
+	alternatives := closest(value, names, maxFuzzyTerms)
+	if len(alternatives) == 0 {
+		*notes = append(*notes, fmt.Sprintf("No tag %q", n.value))
+		return n
+	}
+
+	for i := range alternatives {
+		alternatives[i].term = tags[alternatives[i].term]
+	}
+	n.tagAlts = alternatives
+	*notes = append(*notes, fmt.Sprintf("No tag %q; did you mean %q?", n.value, alternatives[0].term))
+	return n
+}
+
+func joinTerms(terms []fuzzyTerm) string {
+	quoted := make([]string, len(terms))
+	for i, t := range terms {
+		quoted[i] = fmt.Sprintf("%q", t.term)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// rewrite rebuilds the tree with fn applied to every text node
+func rewrite(node Node, fn func(textNode) (textNode, error)) (Node, error) {
+	switch n := node.(type) {
+	case textNode:
+		return fn(n)
+	case andNode:
+		children, err := rewriteAll(n.children, fn)
+		return andNode{children}, err
+	case orNode:
+		children, err := rewriteAll(n.children, fn)
+		return orNode{children}, err
+	case notNode:
+		child, err := rewrite(n.child, fn)
+		return notNode{child}, err
+	}
+	return node, nil
+}
+
+func rewriteAll(nodes []Node, fn func(textNode) (textNode, error)) ([]Node, error) {
+	rewritten := make([]Node, len(nodes))
+	for i, node := range nodes {
+		var err error
+		if rewritten[i], err = rewrite(node, fn); err != nil {
+			return nil, err
+		}
+	}
+	return rewritten, nil
+}
@@ -0,0 +1,87 @@
+package searcher
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/abhijith/bookmark-cli/internal/models"
+	"github.com/abhijith/bookmark-cli/internal/store"
+)
+
+func TestFuzzySearch(t *testing.T) {
+	ctx := context.Background()
+	st := store.NewMemoryStore()
+	for _, bm := range []models.Bookmark{
+		{URL: "https://kubernetes.io/docs", Title: "Kubernetes documentation", Tags: []string{"devops"}, CreatedAt: 1},
+		{URL: "https://example.com/pg", Title: "PostgreSQL tuning tips", Tags: []string{"databases"}, CreatedAt: 2},
+		{URL: "https://example.com/sicp", Title: "Structure and Interpretation", Tags: []string{"programming"}, CreatedAt: 3},
+	} {
+		bm.ID = store.NewID(bm.URL)
+		if err := st.Put(ctx, bm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  string // title of the only match, "" for none
+		note  string // expected in the notes, "" for no notes
+	}{
+		{"kubernets", "Kubernetes documentation", `also searching "kubernetes"`},
+		{"postgre sql", "PostgreSQL tuning tips", ""},
+		{"#progamming", "Structure and Interpretation", `did you mean "programming"?`},
+		{"#database", "PostgreSQL tuning tips", `did you mean "databases"?`},
+		{"xylophone", "", ""},
+		{"#woodworking", "", `No tag "woodworking"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			opts, err := parseSearchInput(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := searchBookmarks(st, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var titles []string
+			for _, bm := range result.Bookmarks {
+				titles = append(titles, bm.Title)
+			}
+			if tt.want == "" && len(titles) != 0 {
+				t.Errorf("matches = %q, want none", titles)
+			}
+			if tt.want != "" && (len(titles) != 1 || titles[0] != tt.want) {
+				t.Errorf("matches = %q, want %q", titles, tt.want)
+			}
+
+			notes := strings.Join(result.Notes, "\n")
+			if tt.note == "" && notes != "" {
+				t.Errorf("notes = %q, want none", notes)
+			}
+			if !strings.Contains(notes, tt.note) {
+				t.Errorf("notes = %q, want one containing %q", notes, tt.note)
+			}
+		})
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		want int
+	}{
+		{"kubernets", "kubernetes", 1},
+		{"teh", "the", 1},
+		{"postgres", "postrgse", 2},
+		{"café", "cafe", 1},
+		{"", "go", 2},
+		{"rust", "rust", 0},
+	} {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package searcher
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/abhijith/bookmark-cli/internal/models"
+	"github.com/abhijith/bookmark-cli/internal/store"
+)
+
+// Output formats for non-interactive search
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+	FormatTSV  = "tsv"
+)
+
+// csvHeader names the columns written by the csv and tsv formats
+var csvHeader = []string{"id", "title", "url", "description", "tags", "folder", "created_at", "updated_at"}
+
+// templateFuncs are available to --format templates
+var templateFuncs = template.FuncMap{
+	"join": strings.Join,
+	"date": func(unix int64) string { return time.Unix(unix, 0).Format("2006-01-02") },
+}
+
+// parseFormat compiles a --format template; each bookmark is rendered on its own line
+func parseFormat(text string) (*template.Template, error) {
+	tmpl, err := template.New("format").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid format template: %v", err)
+	}
+	return tmpl, nil
+}
+
+// WriteCSV writes bookmarks as CSV with a header row, separated by comma
+func WriteCSV(w io.Writer, bookmarks []models.Bookmark, comma rune) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	cw.Write(csvHeader)
+	for _, bm := range bookmarks {
+		cw.Write([]string{
+			bm.ID,
+			bm.Title,
+			bm.URL,
+			bm.Description,
+			strings.Join(bm.Tags, ","),
+			bm.Folder,
+			strconv.FormatInt(bm.CreatedAt, 10),
+			strconv.FormatInt(bm.UpdatedAt, 10),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeResults prints bookmarks to w in the given format. tmpl is used
+// instead of format when set.
+func writeResults(w io.Writer, result SearchResult, format string, tmpl *template.Template) error {
+	if tmpl != nil {
+		for _, bm := range result.Bookmarks {
+			if err := tmpl.Execute(w, bm); err != nil {
+				return fmt.Errorf("failed to render bookmark %s: %v", bm.ID, err)
+			}
+			fmt.Fprintln(w)
+		}
+		return nil
+	}
+
+	switch format {
+	case FormatJSON:
+		bookmarks := result.Bookmarks
+		if bookmarks == nil {
+			bookmarks = []models.Bookmark{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			Total     int               `json:"total"`
+			Offset    int               `json:"offset"`
+			Bookmarks []models.Bookmark `json:"bookmarks"`
+		}{result.Total, result.Offset, bookmarks})
+	case FormatCSV:
+		return WriteCSV(w, result.Bookmarks, ',')
+	case FormatTSV:
+		return WriteCSV(w, result.Bookmarks, '\t')
+	default:
+		for i, bm := range result.Bookmarks {
+			fmt.Fprintf(w, "%d. %s  %s\n", result.Offset+i+1, store.ShortID(bm.ID), bm.Title)
+			fmt.Fprintf(w, "   %s\n", bm.URL)
+			if len(bm.Tags) > 0 {
+				fmt.Fprintf(w, "   Tags: %s\n", strings.Join(bm.Tags, ", "))
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,710 @@
+package searcher
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/abhijith/bookmark-cli/internal/index"
+	"github.com/abhijith/bookmark-cli/internal/models"
+	"github.com/abhijith/bookmark-cli/internal/store"
+)
+
+// Query language
+//
+//	expr    := or
+//	or      := and ("OR" and)*
+//	and     := unary (["AND"] unary)*
+//	unary   := ("NOT" | "-") unary | primary
+//	primary := "(" expr ")" | term
+//	term    := [field ":"] (word | "quoted phrase")
+//
+// Fields: title, desc (description), url, site, tag, folder, before, after.
+// Tags are a hierarchy separated by "/", so tag:dev also matches dev/go.
+// The older shortcuts still work: /word, #tag, @date (first is the start of
+// the range, second the end) and !llm.
+
+// Node is an expression of the search query language
+type Node interface {
+	Match(bm models.Bookmark) bool
+}
+
+type andNode struct{ children []Node }
+type orNode struct{ children []Node }
+type notNode struct{ child Node }
+
+// textNode matches tokens (or a consecutive phrase) within one field; an
+// empty field means title, description, URL and tags
+type textNode struct {
+	field  string
+	value  string
+	tokens []string
+	phrase bool
+
+	// Filled in by expandFuzzy: joins[i] is tokens[i]+tokens[i+1] when that
+	// is an indexed term, fuzzy holds close terms for tokens without hits
+	// and tagAlts close tags for an unknown tag
+	joins   map[int]string
+	fuzzy   map[string][]fuzzyTerm
+	tagAlts []fuzzyTerm
+}
+
+// dateNode compares CreatedAt with the start of a day
+type dateNode struct {
+	before bool
+	at     int64
+}
+
+type matchAll struct{}
+
+func (n andNode) Match(bm models.Bookmark) bool {
+	for _, child := range n.children {
+		if !child.Match(bm) {
+			return false
+		}
+	}
+	return true
+}
+
+func (n orNode) Match(bm models.Bookmark) bool {
+	for _, child := range n.children {
+		if child.Match(bm) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n notNode) Match(bm models.Bookmark) bool {
+	return !n.child.Match(bm)
+}
+
+func (n dateNode) Match(bm models.Bookmark) bool {
+	if n.before {
+		return bm.CreatedAt < n.at
+	}
+	return bm.CreatedAt >= n.at
+}
+
+func (matchAll) Match(bm models.Bookmark) bool {
+	return true
+}
+
+func (n textNode) Match(bm models.Bookmark) bool {
+	switch n.field {
+	case "title":
+		return n.matchTokens(index.Tokenize(bm.Title))
+	case "desc":
+		return n.matchTokens(index.Tokenize(bm.Description))
+	case "url":
+		return strings.Contains(strings.ToLower(bm.URL), strings.ToLower(n.value))
+	case "site":
+		return matchSite(bm.URL, n.value)
+	case "tag":
+		for _, tag := range bm.Tags {
+			if store.TagMatches(tag, n.value) {
+				return true
+			}
+			for _, alt := range n.tagAlts {
+				if store.TagMatches(tag, alt.term) {
+					return true
+				}
+			}
+		}
+		return false
+	case "folder":
+		return inFolder(bm.Folder, n.value)
+	}
+
+	if n.phrase {
+		return n.matchTokens(index.Tokenize(bm.Title)) ||
+			n.matchTokens(index.Tokenize(bm.Description)) ||
+			n.matchTokens(index.URLTokens(bm.URL))
+	}
+	return n.matchTokens(allTokens(bm))
+}
+
+// matchTokens checks every query token against field tokens: each must
+// prefix some field token, or for a phrase they must prefix consecutive ones
+func (n textNode) matchTokens(fieldTokens []string) bool {
+	if !n.phrase {
+		return n.matchFrom(fieldTokens, 0)
+	}
+
+	for start := 0; start+len(n.tokens) <= len(fieldTokens); start++ {
+		matched := true
+		for i, q := range n.tokens {
+			// Inner words of a phrase must match whole tokens; the last may be a prefix
+			token := fieldTokens[start+i]
+			if token != q && !(i == len(n.tokens)-1 && strings.HasPrefix(token, q)) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// matchFrom matches tokens[i:], letting a joinable pair match as one term
+func (n textNode) matchFrom(fieldTokens []string, i int) bool {
+	if i >= len(n.tokens) {
+		return true
+	}
+	if n.matchToken(fieldTokens, n.tokens[i]) && n.matchFrom(fieldTokens, i+1) {
+		return true
+	}
+	if joined, ok := n.joins[i]; ok && hasPrefixToken(fieldTokens, joined) {
+		return n.matchFrom(fieldTokens, i+2)
+	}
+	return false
+}
+
+// matchToken matches one query token directly or through its fuzzy alternatives
+func (n textNode) matchToken(fieldTokens []string, q string) bool {
+	if hasPrefixToken(fieldTokens, q) {
+		return true
+	}
+	for _, alt := range n.fuzzy[q] {
+		for _, token := range fieldTokens {
+			if token == alt.term {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasPrefixToken(tokens []string, prefix string) bool {
+	for _, token := range tokens {
+		if strings.HasPrefix(token, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// allTokens returns the tokens of every searchable field
+func allTokens(bm models.Bookmark) []string {
+	tokens := index.Tokenize(bm.Title)
+	tokens = append(tokens, index.Tokenize(bm.Description)...)
+	tokens = append(tokens, index.URLTokens(bm.URL)...)
+	for _, tag := range bm.Tags {
+		tokens = append(tokens, index.Tokenize(tag)...)
+	}
+	return tokens
+}
+
+// matchSite reports whether rawURL is on site or one of its subdomains
+func matchSite(rawURL, site string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	site = strings.TrimPrefix(strings.ToLower(site), "www.")
+	return host == site || strings.HasSuffix(host, "."+site)
+}
+
+// inFolder reports whether the /-separated path contains folder as a run of
+// whole segments
+func inFolder(path, folder string) bool {
+	path = "/" + strings.ToLower(strings.Trim(path, "/")) + "/"
+	folder = "/" + strings.ToLower(strings.Trim(folder, "/")) + "/"
+	return strings.Contains(path, folder)
+}
+
+// fieldAliases maps qualifier names to canonical fields
+var fieldAliases = map[string]string{
+	"title":       "title",
+	"desc":        "desc",
+	"description": "desc",
+	"url":         "url",
+	"site":        "site",
+	"tag":         "tag",
+	"folder":      "folder",
+	"before":      "before",
+	"after":       "after",
+}
+
+type itemKind int
+
+const (
+	itemTerm itemKind = iota
+	itemLParen
+	itemRParen
+	itemAnd
+	itemOr
+	itemNot
+)
+
+// item is a lexed piece of the query
+type item struct {
+	kind   itemKind
+	field  string
+	value  string
+	phrase bool
+	negate bool
+}
+
+// lex splits a query into items
+func lex(input string) ([]item, error) {
+	var items []item
+	runes := []rune(input)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case r == '(':
+			items = append(items, item{kind: itemLParen})
+			i++
+			continue
+		case r == ')':
+			items = append(items, item{kind: itemRParen})
+			i++
+			continue
+		}
+
+		it := item{kind: itemTerm}
+		if r == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) {
+			if runes[i+1] == '(' {
+				items = append(items, item{kind: itemNot})
+				i++
+				continue
+			}
+			it.negate = true
+			i++
+		}
+
+		// Qualifier prefix, only when it names a known field
+		if j := i; j < len(runes) && runes[j] != '"' {
+			for j < len(runes) && runes[j] != ':' && !unicode.IsSpace(runes[j]) && runes[j] != '(' && runes[j] != ')' {
+				j++
+			}
+			if j < len(runes) && runes[j] == ':' {
+				if field, ok := fieldAliases[strings.ToLower(string(runes[i:j]))]; ok {
+					it.field = field
+					i = j + 1
+				}
+			}
+		}
+
+		if i < len(runes) && runes[i] == '"' {
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end >= len(runes) {
+				return nil, fmt.Errorf("unterminated quote")
+			}
+			it.value = string(runes[i+1 : end])
+			it.phrase = true
+			i = end + 1
+		} else {
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '(' && runes[i] != ')' {
+				i++
+			}
+			it.value = string(runes[start:i])
+		}
+
+		if it.field == "" && !it.phrase && !it.negate {
+			switch it.value {
+			case "AND":
+				it = item{kind: itemAnd}
+			case "OR":
+				it = item{kind: itemOr}
+			case "NOT":
+				it = item{kind: itemNot}
+			}
+		}
+		if it.kind == itemTerm && it.value == "" && !it.phrase {
+			if it.field == "" {
+				continue
+			}
+			return nil, fmt.Errorf("missing value after %s:", it.field)
+		}
+		items = append(items, it)
+	}
+	return items, nil
+}
+
+// parser turns lexed items into a Node tree
+type parser struct {
+	items      []item
+	pos        int
+	dates      int
+	includeLLM bool
+}
+
+// ParseQuery parses the query language into an expression tree; the second
+// result reports the !llm flag
+func ParseQuery(input string) (Node, bool, error) {
+	items, err := lex(input)
+	if err != nil {
+		return nil, false, err
+	}
+
+	p := &parser{items: items}
+	if len(items) == 0 {
+		return matchAll{}, false, nil
+	}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, false, err
+	}
+	if p.pos < len(p.items) {
+		return nil, false, fmt.Errorf("unexpected )")
+	}
+	return node, p.includeLLM, nil
+}
+
+func (p *parser) peek() (item, bool) {
+	if p.pos >= len(p.items) {
+		return item{}, false
+	}
+	return p.items[p.pos], true
+}
+
+func (p *parser) parseOr() (Node, error) {
+	var children []Node
+	for {
+		node, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		it, ok := p.peek()
+		more := ok && it.kind == itemOr
+		if node == nil {
+			// Only "" and "()" may be empty; "a OR" must not match everything
+			if more || len(children) > 0 {
+				return nil, fmt.Errorf("OR needs an operand on both sides")
+			}
+			node = matchAll{}
+		}
+		children = append(children, node)
+
+		if !more {
+			break
+		}
+		p.pos++
+	}
+
+	if len(children) == 1 {
+		return children[0], nil
+	}
+	return orNode{children}, nil
+}
+
+// parseAnd returns nil when there is no operand before the next OR, ) or
+// the end
+func (p *parser) parseAnd() (Node, error) {
+	var children []Node
+	operands := 0
+	for {
+		it, ok := p.peek()
+		if !ok || it.kind == itemOr || it.kind == itemRParen {
+			break
+		}
+		if it.kind == itemAnd {
+			p.pos++
+			next, ok := p.peek()
+			if operands == 0 || !ok || next.kind == itemAnd || next.kind == itemOr || next.kind == itemRParen {
+				return nil, fmt.Errorf("AND needs an operand on both sides")
+			}
+			continue
+		}
+
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		operands++
+		if node == nil {
+			continue
+		}
+
+		// Neighbouring words on the same field become one node, so the
+		// words keep their order for joining ("postgre sql")
+		if text, ok := node.(textNode); ok && len(children) > 0 {
+			if prev, ok := children[len(children)-1].(textNode); ok && mergeable(prev, text) {
+				prev.value += " " + text.value
+				prev.tokens = append(prev.tokens, text.tokens...)
+				children[len(children)-1] = prev
+				continue
+			}
+		}
+		children = append(children, node)
+	}
+
+	switch {
+	case operands == 0:
+		return nil, nil
+	case len(children) == 0:
+		// Only flags such as !llm
+		return matchAll{}, nil
+	case len(children) == 1:
+		return children[0], nil
+	}
+	return andNode{children}, nil
+}
+
+// mergeable reports whether two plain word nodes can be ANDed as one
+func mergeable(a, b textNode) bool {
+	if a.phrase || b.phrase || a.field != b.field {
+		return false
+	}
+	switch a.field {
+	case "", "title", "desc":
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	it, _ := p.peek()
+	if it.kind == itemNot {
+		p.pos++
+		if _, ok := p.peek(); !ok {
+			return nil, fmt.Errorf("NOT needs an operand")
+		}
+		child, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		// Flags, words without tokens and stray operators leave nothing to negate
+		if child == nil {
+			return nil, fmt.Errorf("NOT needs an operand")
+		}
+		return notNode{child}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Node, error) {
+	it, _ := p.peek()
+	p.pos++
+
+	if it.kind == itemLParen {
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if next, ok := p.peek(); !ok || next.kind != itemRParen {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return node, nil
+	}
+
+	node, err := p.term(it)
+	if err != nil || node == nil {
+		return node, err
+	}
+	if it.negate {
+		return notNode{node}, nil
+	}
+	return node, nil
+}
+
+// term builds the leaf node for a lexed term, translating the older shortcuts
+func (p *parser) term(it item) (Node, error) {
+	field, value := it.field, it.value
+	if field == "" && !it.phrase {
+		switch {
+		case strings.HasPrefix(value, "#") && len(value) > 1:
+			field, value = "tag", value[1:]
+		case strings.HasPrefix(value, "/") && len(value) > 1:
+			value = value[1:]
+		case strings.HasPrefix(value, "@") && len(value) > 1:
+			// First @date starts the range, the second ends it
+			field, value = "after", value[1:]
+			if p.dates > 0 {
+				field = "before"
+			}
+			p.dates++
+		case strings.HasPrefix(value, "!"):
+			p.includeLLM = true
+			return nil, nil
+		}
+	}
+
+	switch field {
+	case "before", "after":
+		t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q (use YYYY-MM-DD)", value)
+		}
+		return dateNode{before: field == "before", at: t.Unix()}, nil
+	case "url", "site", "tag", "folder":
+		return textNode{field: field, value: value, phrase: it.phrase}, nil
+	}
+
+	tokens := index.Tokenize(value)
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+	return textNode{field: field, value: value, tokens: tokens, phrase: it.phrase}, nil
+}
+
+// requiredTokens returns text tokens every match must contain, so the store
+// can narrow candidates through the term index before evaluating the tree.
+// Tokens that may match through a join or a fuzzy alternative, and tokens too
+// short to be indexed, are left out; tags are narrowed through the tag index
+// instead (see requiredTags).
+func requiredTokens(node Node) []string {
+	switch n := node.(type) {
+	case textNode:
+		switch n.field {
+		case "", "title", "desc":
+			var tokens []string
+			for i, token := range n.tokens {
+				_, joinNext := n.joins[i]
+				_, joinPrev := n.joins[i-1]
+				if joinNext || joinPrev || n.fuzzy[token] != nil || len([]rune(token)) < index.MinTermLength {
+					continue
+				}
+				tokens = append(tokens, token)
+			}
+			return tokens
+		}
+	case andNode:
+		var tokens []string
+		for _, child := range n.children {
+			tokens = append(tokens, requiredTokens(child)...)
+		}
+		return tokens
+	}
+	return nil
+}
+
+// requiredTags returns the tags every match must carry, so the store can
+// answer them from its tag index; tags widened to fuzzy alternatives are left
+// to Match
+func requiredTags(node Node) []string {
+	switch n := node.(type) {
+	case textNode:
+		if n.field == "tag" && len(n.tagAlts) == 0 {
+			return []string{n.value}
+		}
+	case andNode:
+		var tags []string
+		for _, child := range n.children {
+			tags = append(tags, requiredTags(child)...)
+		}
+		return tags
+	}
+	return nil
+}
+
+// indexed reports whether the store constraints built from node select
+// exactly its matches, so Match can be skipped: it must be top-level dates
+// (see dateBounds) and tags without fuzzy alternatives. Text is always
+// rechecked, as the term index matches prefixes of every field at once.
+func indexed(node Node) bool {
+	nodes := []Node{node}
+	if and, ok := node.(andNode); ok {
+		nodes = and.children
+	}
+
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case matchAll, dateNode:
+		case textNode:
+			if n.field != "tag" || len(n.tagAlts) > 0 {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// scoringTokens returns the positive text tokens used for relevance
+// ranking, with the weight each contributes: fuzzy alternatives lose
+// fuzzyPenalty per edit
+func scoringTokens(node Node) map[string]float64 {
+	weights := make(map[string]float64)
+	collectScoringTokens(node, weights)
+	return weights
+}
+
+func collectScoringTokens(node Node, weights map[string]float64) {
+	add := func(token string, weight float64) {
+		if weight > weights[token] {
+			weights[token] = weight
+		}
+	}
+
+	switch n := node.(type) {
+	case textNode:
+		switch n.field {
+		case "", "title", "desc":
+			for _, token := range n.tokens {
+				add(token, 1)
+				for _, alt := range n.fuzzy[token] {
+					add(alt.term, math.Pow(fuzzyPenalty, float64(alt.dist)))
+				}
+			}
+			for _, joined := range n.joins {
+				add(joined, 1)
+			}
+		case "tag":
+			for _, token := range index.Tokenize(n.value) {
+				add(token, 1)
+			}
+			for _, alt := range n.tagAlts {
+				for _, token := range index.Tokenize(alt.term) {
+					add(token, math.Pow(fuzzyPenalty, float64(alt.dist)))
+				}
+			}
+		}
+	case andNode:
+		for _, child := range n.children {
+			collectScoringTokens(child, weights)
+		}
+	case orNode:
+		for _, child := range n.children {
+			collectScoringTokens(child, weights)
+		}
+	}
+}
+
+// dateBounds extracts the CreatedAt range implied by top-level date terms
+func dateBounds(node Node) (from, to *int64) {
+	var nodes []Node
+	if and, ok := node.(andNode); ok {
+		nodes = and.children
+	} else {
+		nodes = []Node{node}
+	}
+
+	for _, n := range nodes {
+		d, ok := n.(dateNode)
+		if !ok {
+			continue
+		}
+		if d.before {
+			end := d.at - 1
+			if to == nil || end < *to {
+				to = &end
+			}
+		} else {
+			start := d.at
+			if from == nil || start > *from {
+				from = &start
+			}
+		}
+	}
+	return from, to
+}
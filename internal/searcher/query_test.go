@@ -0,0 +1,137 @@
+package searcher
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/abhijith/bookmark-cli/internal/models"
+	"github.com/abhijith/bookmark-cli/internal/store"
+)
+
+func TestParseQueryErrors(t *testing.T) {
+	for _, input := range []string{
+		"NOT !llm",
+		"go NOT .",
+		"NOT #",
+		"NOT AND",
+		"NOT -",
+		"NOT -tag:x OR",
+		"NOT )",
+		"a OR",
+		"OR go",
+		"a OR OR b",
+		"(a OR)",
+		"a AND",
+		"AND go",
+		"a AND AND b",
+		"a AND OR b",
+		"(a AND)",
+		"NOT",
+		"(a",
+		"a)",
+		`"open`,
+		"title:",
+		"before:yesterday",
+	} {
+		t.Run(input, func(t *testing.T) {
+			node, _, err := ParseQuery(input)
+			if err == nil {
+				t.Fatalf("ParseQuery(%q) = %#v, want an error", input, node)
+			}
+		})
+	}
+}
+
+func TestParseQueryMatches(t *testing.T) {
+	bm := models.Bookmark{
+		URL:         "https://github.com/golang/go",
+		Title:       "The Go Programming Language",
+		Description: "Source for the Go compiler",
+		Tags:        []string{"dev/go", "Archived"},
+		Folder:      "Bookmarks Bar/Dev",
+	}
+
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"", true},
+		{"()", true},
+		{"golang", true},
+		{"rust", false},
+		{"go AND rust", false},
+		{"go OR rust", true},
+		{"rust OR python", false},
+		{"NOT rust", true},
+		{"NOT -tag:x", false},
+		{"-rust", true},
+		{"go -tag:archived", false},
+		{"tag:dev", true},
+		{"#dev/go", true},
+		{"site:github.com", true},
+		{"site:gitlab.com", false},
+		{"folder:dev", true},
+		{`"programming language"`, true},
+		{`"language programming"`, false},
+		{"title:compiler", false},
+		{"desc:compiler", true},
+		{"(rust OR go) NOT python", true},
+		{"go !llm", true},
+		{"NOT (go OR rust)", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			node, _, err := ParseQuery(tt.input)
+			if err != nil {
+				t.Fatalf("ParseQuery(%q): %v", tt.input, err)
+			}
+			if got := node.Match(bm); got != tt.want {
+				t.Errorf("ParseQuery(%q).Match = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseQueryLLMFlag(t *testing.T) {
+	for input, want := range map[string]bool{
+		"go":        false,
+		"go !llm":   true,
+		"!llm":      true,
+		"-!llm go":  true,
+		"(go !llm)": true,
+	} {
+		_, includeLLM, err := ParseQuery(input)
+		if err != nil {
+			t.Fatalf("ParseQuery(%q): %v", input, err)
+		}
+		if includeLLM != want {
+			t.Errorf("ParseQuery(%q) includeLLM = %v, want %v", input, includeLLM, want)
+		}
+	}
+}
+
+func TestSearchOneLetterWord(t *testing.T) {
+	ctx := context.Background()
+	st := store.NewMemoryStore()
+	bm := models.Bookmark{URL: "https://example.org/kr", Title: "The C Programming Language", CreatedAt: 1}
+	bm.ID = store.NewID(bm.URL)
+	if err := st.Put(ctx, bm); err != nil {
+		t.Fatal(err)
+	}
+
+	opts, err := parseSearchInput("c lang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := requiredTokens(opts.Expr); !reflect.DeepEqual(got, []string{"lang"}) {
+		t.Errorf("requiredTokens = %q, want [lang]", got)
+	}
+	result, err := searchBookmarks(st, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 1 {
+		t.Errorf("search for %q found %d bookmarks, want 1", opts.Query, result.Total)
+	}
+}
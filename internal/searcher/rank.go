@@ -0,0 +1,146 @@
+package searcher
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/abhijith/bookmark-cli/internal/index"
+	"github.com/abhijith/bookmark-cli/internal/models"
+)
+
+const (
+	SortRelevance = "relevance"
+	SortDate      = "date"
+	SortTitle     = "title"
+)
+
+// BM25 parameters
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+
+	// prefixWeight discounts a token that only starts a term ("gol" in "golang")
+	prefixWeight = 0.5
+)
+
+// field is one searchable part of a bookmark with its boost
+type field struct {
+	name   string
+	boost  float64
+	tokens func(bm models.Bookmark) []string
+}
+
+var fields = []field{
+	{"title", 3.0, func(bm models.Bookmark) []string { return index.Tokenize(bm.Title) }},
+	{"tags", 2.0, func(bm models.Bookmark) []string {
+		var tokens []string
+		for _, tag := range bm.Tags {
+			tokens = append(tokens, index.Tokenize(tag)...)
+		}
+		return tokens
+	}},
+	{"url", 1.5, func(bm models.Bookmark) []string { return index.URLTokens(bm.URL) }},
+	{"description", 1.0, func(bm models.Bookmark) []string { return index.Tokenize(bm.Description) }},
+}
+
+// scorer computes BM25F scores for one query over a candidate set
+type scorer struct {
+	queryTokens []string
+	weights     map[string]float64
+	idf         map[string]float64
+	avgLen      map[string]float64
+}
+
+// newScorer prepares IDF weights from the corpus size and document
+// frequencies; average field lengths are taken from the candidates.
+// weights scales the contribution of each query token.
+func newScorer(weights map[string]float64, total int, docFreq map[string]int, candidates []models.Bookmark) *scorer {
+	s := &scorer{
+		weights: weights,
+		idf:     make(map[string]float64, len(weights)),
+		avgLen:  make(map[string]float64, len(fields)),
+	}
+
+	for token := range weights {
+		s.queryTokens = append(s.queryTokens, token)
+		df := float64(docFreq[token])
+		s.idf[token] = math.Log(1 + (float64(total)-df+0.5)/(df+0.5))
+	}
+	sort.Strings(s.queryTokens)
+
+	if len(candidates) > 0 {
+		for _, f := range fields {
+			sum := 0
+			for _, bm := range candidates {
+				sum += len(f.tokens(bm))
+			}
+			s.avgLen[f.name] = float64(sum) / float64(len(candidates))
+		}
+	}
+	return s
+}
+
+// score returns the BM25F relevance of bm
+func (s *scorer) score(bm models.Bookmark) float64 {
+	tf := make(map[string]float64, len(s.queryTokens))
+	for _, f := range fields {
+		tokens := f.tokens(bm)
+		if len(tokens) == 0 {
+			continue
+		}
+		norm := 1.0
+		if avg := s.avgLen[f.name]; avg > 0 {
+			norm = 1 - bm25B + bm25B*float64(len(tokens))/avg
+		}
+		for _, q := range s.queryTokens {
+			hits := 0.0
+			for _, token := range tokens {
+				switch {
+				case token == q:
+					hits++
+				case strings.HasPrefix(token, q):
+					hits += prefixWeight
+				}
+			}
+			tf[q] += f.boost * hits / norm
+		}
+	}
+
+	score := 0.0
+	for _, q := range s.queryTokens {
+		score += s.weights[q] * s.idf[q] * tf[q] / (bm25K1 + tf[q])
+	}
+	return score
+}
+
+// sortResults orders results in place; ties fall back to newest first
+func sortResults(results []models.Bookmark, order string, scores map[string]float64) {
+	newer := func(i, j int) bool {
+		if results[i].CreatedAt != results[j].CreatedAt {
+			return results[i].CreatedAt > results[j].CreatedAt
+		}
+		return results[i].ID < results[j].ID
+	}
+
+	switch order {
+	case SortTitle:
+		sort.SliceStable(results, func(i, j int) bool {
+			a, b := strings.ToLower(results[i].Title), strings.ToLower(results[j].Title)
+			if a != b {
+				return a < b
+			}
+			return newer(i, j)
+		})
+	case SortRelevance:
+		sort.SliceStable(results, func(i, j int) bool {
+			a, b := scores[results[i].ID], scores[results[j].ID]
+			if a != b {
+				return a > b
+			}
+			return newer(i, j)
+		})
+	default:
+		sort.SliceStable(results, newer)
+	}
+}
@@ -0,0 +1,46 @@
+package searcher
+
+import (
+	"context"
+	"testing"
+
+	"github.com/abhijith/bookmark-cli/internal/models"
+	"github.com/abhijith/bookmark-cli/internal/store"
+)
+
+func TestRelevanceTitleOutranksURLSubstring(t *testing.T) {
+	ctx := context.Background()
+	st := store.NewMemoryStore()
+	for _, bm := range []models.Bookmark{
+		{URL: "https://example.org/containers", Title: "Docker", CreatedAt: 1},
+		{URL: "https://example.com/dockerhub-mirror", Title: "Mirror setup notes", CreatedAt: 2},
+		{URL: "https://example.net/recipes", Title: "Weeknight recipes", CreatedAt: 3},
+		{URL: "https://example.net/garden", Title: "Garden planner", CreatedAt: 4},
+	} {
+		bm.ID = store.NewID(bm.URL)
+		if err := st.Put(ctx, bm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for order, want := range map[string]string{
+		SortDate:      "Mirror setup notes",
+		SortRelevance: "Docker",
+	} {
+		opts, err := parseSearchInput("docker")
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts.Sort = order
+		result, err := searchBookmarks(st, opts)
+		if err != nil {
+			t.Fatalf("%s: %v", order, err)
+		}
+		if result.Total != 2 || len(result.Bookmarks) != 2 {
+			t.Fatalf("%s: got %d of %d matches, want 2", order, len(result.Bookmarks), result.Total)
+		}
+		if got := result.Bookmarks[0].Title; got != want {
+			t.Errorf("%s: first result = %q, want %q", order, got, want)
+		}
+	}
+}
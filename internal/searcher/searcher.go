@@ -3,44 +3,117 @@ package searcher
 import (
 	"bufio"
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
-	"sort"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/abhijith/bookmark-cli/internal/models"
-	"github.com/go-redis/redis/v8"
+	"github.com/abhijith/bookmark-cli/internal/store"
 	"github.com/urfave/cli/v2"
-)
-
-const (
-	RedisBookmarksKey = "bookmarks:index"
+	"golang.org/x/term"
 )
 
 type SearchOptions struct {
 	Query      string
-	Tags       []string
-	DateFrom   *int64
-	DateTo     *int64
+	Expr       Node
 	Limit      int
+	Offset     int
+	Sort       string
 	IncludeLLM bool
 }
 
-func SearchCommand(redisClient *redis.Client) cli.ActionFunc {
+// SearchResult is one page of matches out of Total
+type SearchResult struct {
+	Bookmarks []models.Bookmark
+	Total     int
+	Offset    int
+	// Notes explains fuzzy substitutions made for words or tags that matched nothing
+	Notes []string
+}
+
+// SearchCommand runs the query given as arguments and prints the results.
+// Without a query it opens the full-screen search on a terminal; -i (or a
+// non-terminal stdin) uses the line-based prompt instead.
+func SearchCommand(st store.Store) cli.ActionFunc {
 	return func(c *cli.Context) error {
-		return InteractiveSearch(redisClient)
+		order := c.String("sort")
+		switch order {
+		case SortRelevance, SortDate, SortTitle:
+		default:
+			return cli.Exit(fmt.Sprintf("Unknown sort order %q (use date, relevance or title)", order), 1)
+		}
+
+		query := strings.TrimSpace(strings.Join(c.Args().Slice(), " "))
+		if query == "" && !c.Bool("interactive") &&
+			term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd())) {
+			return TUISearch(st, order)
+		}
+		if c.Bool("interactive") || query == "" {
+			return InteractiveSearch(st, order)
+		}
+
+		format := FormatText
+		formats := 0
+		for _, f := range []string{FormatJSON, FormatCSV, FormatTSV} {
+			if c.Bool(f) {
+				format = f
+				formats++
+			}
+		}
+		var tmpl *template.Template
+		if c.IsSet("format") {
+			formats++
+			var err error
+			if tmpl, err = parseFormat(c.String("format")); err != nil {
+				return cli.Exit(err.Error(), 1)
+			}
+		}
+		if formats > 1 {
+			return cli.Exit("Use only one of --json, --csv, --tsv and --format", 1)
+		}
+
+		opts, err := parseSearchInput(query)
+		if err != nil {
+			return cli.Exit(fmt.Sprintf("Invalid query: %v", err), 1)
+		}
+		opts.Sort = order
+		opts.Limit = c.Int("limit")
+		opts.Offset = c.Int("offset")
+
+		result, err := searchBookmarks(st, opts)
+		if err != nil {
+			return fmt.Errorf("search failed: %v", err)
+		}
+
+		// Notes go to stderr so they never end up in piped output
+		for _, note := range result.Notes {
+			fmt.Fprintln(os.Stderr, note)
+		}
+		if err := writeResults(os.Stdout, result, format, tmpl); err != nil {
+			return err
+		}
+		if len(result.Bookmarks) == 0 {
+			return cli.Exit("", 1)
+		}
+		return nil
 	}
 }
 
-func InteractiveSearch(redisClient *redis.Client) error {
+func InteractiveSearch(st store.Store, order string) error {
 	fmt.Println("Interactive Bookmark Search (Ctrl+C to exit)")
 	fmt.Println("Shortcuts: /search, #tag, @date, !llm")
+	fmt.Println("Operators: AND, OR, NOT/-, ( ), \"phrase\", title: desc: url: site: tag: folder: before: after:")
 	fmt.Println("Examples:")
 	fmt.Println("  /golang programming")
 	fmt.Println("  #database #redis")
 	fmt.Println("  @2023-01-01 @2023-12-31")
+	fmt.Println("  (redis OR postgres) site:github.com -tag:archived")
+	fmt.Println("Type next/prev to page through results")
+
+	var last *SearchOptions
+	var lastTotal int
 
 	scanner := bufio.NewScanner(os.Stdin)
 	for {
@@ -54,138 +127,213 @@ func InteractiveSearch(redisClient *redis.Client) error {
 			continue
 		}
 
-		opts := parseSearchInput(input)
-		results, err := searchBookmarks(redisClient, opts)
+		var opts SearchOptions
+		var err error
+		switch input {
+		case "next", "prev":
+			if last == nil {
+				fmt.Println("No search to page through yet")
+				continue
+			}
+			opts = *last
+			if input == "next" {
+				if opts.Offset+opts.Limit >= lastTotal {
+					fmt.Println("Already on the last page")
+					continue
+				}
+				opts.Offset += opts.Limit
+			} else {
+				if opts.Offset == 0 {
+					fmt.Println("Already on the first page")
+					continue
+				}
+				opts.Offset -= opts.Limit
+				if opts.Offset < 0 {
+					opts.Offset = 0
+				}
+			}
+		default:
+			opts, err = parseSearchInput(input)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				continue
+			}
+			opts.Sort = order
+		}
+
+		result, err := searchBookmarks(st, opts)
 		if err != nil {
 			fmt.Printf("Error: %v\n", err)
 			continue
 		}
+		last, lastTotal = &opts, result.Total
 
-		displayResults(results)
+		displayResults(result)
 	}
 	return nil
 }
 
-func searchBookmarks(redisClient *redis.Client, opts SearchOptions) ([]models.Bookmark, error) {
-	ctx := context.Background()
+// Find returns every bookmark matching query, newest first
+func Find(st store.Store, query string) (SearchResult, error) {
+	opts, err := parseSearchInput(query)
+	if err != nil {
+		return SearchResult{}, err
+	}
+	opts.Sort = SortDate
+	opts.Limit = 0
+	return searchBookmarks(st, opts)
+}
 
-	// Get all bookmarks
-	zRange := redisClient.ZRangeWithScores(ctx, RedisBookmarksKey, 0, -1)
-	results, err := zRange.Result()
+// searchBookmarks returns the page of matches selected by opts.Offset and
+// opts.Limit together with the total number of matches
+func searchBookmarks(st store.Store, opts SearchOptions) (SearchResult, error) {
+	ctx := context.Background()
+	expr, notes, err := expandFuzzy(ctx, st, opts.Expr)
 	if err != nil {
-		return nil, err
+		return SearchResult{}, err
 	}
+	weights := scoringTokens(expr)
+	from, to := dateBounds(expr)
 
-	var matches []models.Bookmark
-	for _, z := range results {
-		var bm models.Bookmark
-		if err := json.Unmarshal([]byte(z.Member.(string)), &bm); err != nil {
-			continue
-		}
+	// Without query terms there is nothing to score, so relevance means newest first
+	order := opts.Sort
+	if order == SortRelevance && len(weights) == 0 {
+		order = SortDate
+	}
 
-		// Apply filters
-		if !matchesFilters(bm, opts) {
-			continue
-		}
+	// Text terms, tags and dates are resolved by the store's indexes. Match
+	// is left out when the indexes already say everything the query does.
+	q := store.Query{
+		From:    from,
+		To:      to,
+		Terms:   uniqueTokens(requiredTokens(expr)),
+		Tags:    requiredTags(expr),
+		Reverse: true,
+	}
+	if !indexed(expr) {
+		q.Match = expr.Match
+	}
 
-		matches = append(matches, bm)
-		if len(matches) >= opts.Limit {
-			break
+	// Date order comes straight from the store, which stops once the page
+	// is complete; other orders need the full set to rank
+	if order == SortDate && opts.Limit > 0 {
+		page := q
+		page.Offset, page.Limit = opts.Offset, opts.Limit
+		bookmarks, err := st.Query(ctx, page)
+		if err != nil {
+			return SearchResult{}, err
+		}
+		total, err := st.CountQuery(ctx, q)
+		if err != nil {
+			return SearchResult{}, err
 		}
+		return SearchResult{Bookmarks: bookmarks, Total: total, Offset: opts.Offset, Notes: notes}, nil
+	}
+
+	matches, err := st.Query(ctx, q)
+	if err != nil {
+		return SearchResult{}, err
 	}
 
-	// Sort by relevance (simplified)
-	sort.Slice(matches, func(i, j int) bool {
-		return matches[i].CreatedAt > matches[j].CreatedAt
-	})
+	if order != SortDate {
+		var scores map[string]float64
+		if order == SortRelevance {
+			scores, err = scoreMatches(ctx, st, weights, matches)
+			if err != nil {
+				return SearchResult{}, err
+			}
+		}
+		sortResults(matches, order, scores)
+	}
 
-	return matches, nil
+	result := paginate(matches, opts.Offset, opts.Limit)
+	result.Notes = notes
+	return result, nil
 }
 
-func matchesFilters(bm models.Bookmark, opts SearchOptions) bool {
-	// Text search
-	if opts.Query != "" {
-		query := strings.ToLower(opts.Query)
-		title := strings.ToLower(bm.Title)
-		desc := strings.ToLower(bm.Description)
-		url := strings.ToLower(bm.URL)
+// paginate cuts the page [offset, offset+limit) out of matches
+func paginate(matches []models.Bookmark, offset, limit int) SearchResult {
+	result := SearchResult{Total: len(matches), Offset: offset}
+	if offset >= len(matches) {
+		return result
+	}
 
-		if !strings.Contains(title, query) &&
-			!strings.Contains(desc, query) &&
-			!strings.Contains(url, query) {
-			return false
-		}
+	end := len(matches)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
 	}
+	result.Bookmarks = matches[offset:end]
+	return result
+}
 
-	// Tag filtering
-	if len(opts.Tags) > 0 {
-		tagMap := make(map[string]bool)
-		for _, tag := range bm.Tags {
-			tagMap[strings.ToLower(tag)] = true
-		}
-		for _, tag := range opts.Tags {
-			if !tagMap[strings.ToLower(tag)] {
-				return false
-			}
-		}
+// scoreMatches computes the BM25F score of every match, keyed by ID
+func scoreMatches(ctx context.Context, st store.Store, weights map[string]float64, matches []models.Bookmark) (map[string]float64, error) {
+	tokens := make([]string, 0, len(weights))
+	for token := range weights {
+		tokens = append(tokens, token)
 	}
 
-	// Date filtering
-	if opts.DateFrom != nil && bm.CreatedAt < *opts.DateFrom {
-		return false
+	total, err := st.Count(ctx)
+	if err != nil {
+		return nil, err
 	}
-	if opts.DateTo != nil && bm.CreatedAt > *opts.DateTo {
-		return false
+	docFreq, err := st.DocFreq(ctx, tokens)
+	if err != nil {
+		return nil, err
 	}
 
-	return true
+	s := newScorer(weights, total, docFreq, matches)
+	scores := make(map[string]float64, len(matches))
+	for _, bm := range matches {
+		scores[bm.ID] = s.score(bm)
+	}
+	return scores, nil
 }
 
-func parseSearchInput(input string) SearchOptions {
-	opts := SearchOptions{
-		Limit: 20,
-	}
-
-	parts := strings.Fields(input)
-	for _, part := range parts {
-		switch {
-		case strings.HasPrefix(part, "/"):
-			opts.Query = strings.TrimPrefix(part, "/")
-		case strings.HasPrefix(part, "#"):
-			opts.Tags = append(opts.Tags, strings.TrimPrefix(part, "#"))
-		case strings.HasPrefix(part, "@"):
-			dateStr := strings.TrimPrefix(part, "@")
-			if t, err := time.Parse("2006-01-02", dateStr); err == nil {
-				if opts.DateFrom == nil {
-					opts.DateFrom = &[]int64{t.Unix()}[0]
-				} else {
-					opts.DateTo = &[]int64{t.Unix()}[0]
-				}
-			}
-		case strings.HasPrefix(part, "!"):
-			opts.IncludeLLM = true
-		default:
-			// If no prefix, treat as search query
-			if opts.Query == "" {
-				opts.Query = part
-			} else {
-				opts.Query += " " + part
-			}
+// uniqueTokens drops repeated tokens, keeping the first occurrence
+func uniqueTokens(tokens []string) []string {
+	seen := make(map[string]bool, len(tokens))
+	var unique []string
+	for _, token := range tokens {
+		if !seen[token] {
+			seen[token] = true
+			unique = append(unique, token)
 		}
 	}
+	return unique
+}
 
-	return opts
+func parseSearchInput(input string) (SearchOptions, error) {
+	expr, includeLLM, err := ParseQuery(input)
+	if err != nil {
+		return SearchOptions{}, err
+	}
+
+	return SearchOptions{
+		Query:      input,
+		Expr:       expr,
+		Limit:      20,
+		IncludeLLM: includeLLM,
+	}, nil
 }
 
-func displayResults(results []models.Bookmark) {
-	if len(results) == 0 {
+func displayResults(result SearchResult) {
+	for _, note := range result.Notes {
+		fmt.Println(note)
+	}
+	if result.Total == 0 {
 		fmt.Println("No results found")
 		return
 	}
+	if len(result.Bookmarks) == 0 {
+		fmt.Printf("No results on this page (%d total)\n", result.Total)
+		return
+	}
 
-	fmt.Printf("Found %d results:\n\n", len(results))
-	for i, bm := range results {
-		fmt.Printf("%d. %s\n", i+1, bm.Title)
+	first, last := result.Offset+1, result.Offset+len(result.Bookmarks)
+	fmt.Printf("Found %d results, showing %d-%d:\n\n", result.Total, first, last)
+	for i, bm := range result.Bookmarks {
+		fmt.Printf("%d. %s  %s\n", result.Offset+i+1, store.ShortID(bm.ID), bm.Title)
 		fmt.Printf("   %s\n", bm.URL)
 		if bm.Description != "" {
 			fmt.Printf("   %s\n", bm.Description)
@@ -193,10 +341,13 @@ func displayResults(results []models.Bookmark) {
 		if len(bm.Tags) > 0 {
 			fmt.Printf("   Tags: %s\n", strings.Join(bm.Tags, ", "))
 		}
+		if bm.Folder != "" {
+			fmt.Printf("   Folder: %s\n", bm.Folder)
+		}
 		fmt.Printf("   Created: %s\n", time.Unix(bm.CreatedAt, 0).Format("2006-01-02"))
 		fmt.Println()
 	}
+	if last < result.Total {
+		fmt.Println("Type next for more results")
+	}
 }
-
-
-
@@ -0,0 +1,496 @@
+package searcher
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/abhijith/bookmark-cli/internal/models"
+	"github.com/abhijith/bookmark-cli/internal/store"
+	"golang.org/x/term"
+)
+
+const (
+	// tuiMaxResults caps how many matches the full-screen search keeps for scrolling
+	tuiMaxResults = 200
+	// previewHeight is the number of lines in the preview pane
+	previewHeight = 6
+)
+
+// ANSI escape sequences used by the full-screen search
+const (
+	ansiAltScreen  = "\x1b[?1049h"
+	ansiMainScreen = "\x1b[?1049l"
+	ansiClear      = "\x1b[H\x1b[2J"
+	ansiClearLine  = "\x1b[K"
+	ansiReverse    = "\x1b[7m"
+	ansiBold       = "\x1b[1m"
+	ansiDim        = "\x1b[2m"
+	ansiReset      = "\x1b[0m"
+)
+
+const tuiHelp = "↑/↓ move  Enter open  ^Y copy URL  ^T edit tags  ^D delete  Esc quit"
+
+// key is one keypress: a named key such as "up" or "ctrl-y", or a typed rune
+type key struct {
+	name string
+	r    rune
+}
+
+// inlinePrompt asks for a line of input on the bottom row; done receives
+// the answer unless the prompt is cancelled
+type inlinePrompt struct {
+	label string
+	input []rune
+	done  func(answer string)
+}
+
+// tui is the state of the full-screen search
+type tui struct {
+	st    store.Store
+	order string
+	out   *bufio.Writer
+
+	query    []rune
+	result   SearchResult
+	selected int
+	top      int
+	status   string
+	prompt   *inlinePrompt
+	quit     bool
+}
+
+// TUISearch runs the full-screen search: results are filtered as the query
+// is typed, with a preview of the selected bookmark and keys to act on it
+func TUISearch(st store.Store, order string) error {
+	fd := int(os.Stdin.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("failed to set up terminal: %v", err)
+	}
+	defer term.Restore(fd, state)
+
+	t := &tui{st: st, order: order, out: bufio.NewWriter(os.Stdout)}
+	t.out.WriteString(ansiAltScreen)
+	defer func() {
+		t.out.WriteString(ansiMainScreen)
+		t.out.Flush()
+	}()
+
+	t.search()
+	for !t.quit {
+		t.render()
+		keys, err := readKeys(os.Stdin)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		for _, k := range keys {
+			t.handle(k)
+		}
+	}
+	return nil
+}
+
+// search reruns the current query, keeping the selection where possible
+func (t *tui) search() {
+	opts, err := parseSearchInput(string(t.query))
+	if err != nil {
+		// Half-typed queries are common; keep the last results until it parses
+		t.status = err.Error()
+		return
+	}
+	opts.Sort = t.order
+	opts.Limit = tuiMaxResults
+
+	result, err := searchBookmarks(t.st, opts)
+	if err != nil {
+		t.status = err.Error()
+		return
+	}
+	t.result = result
+	t.status = strings.Join(result.Notes, "; ")
+	t.selected = clamp(t.selected, 0, len(result.Bookmarks)-1)
+}
+
+// current returns the selected bookmark
+func (t *tui) current() (models.Bookmark, bool) {
+	if t.selected < 0 || t.selected >= len(t.result.Bookmarks) {
+		return models.Bookmark{}, false
+	}
+	return t.result.Bookmarks[t.selected], true
+}
+
+func (t *tui) handle(k key) {
+	if t.prompt != nil {
+		t.handlePrompt(k)
+		return
+	}
+
+	switch k.name {
+	case "esc", "ctrl-c":
+		t.quit = true
+	case "up", "ctrl-p":
+		t.move(-1)
+	case "down", "ctrl-n":
+		t.move(1)
+	case "pgup":
+		t.move(-t.listHeight())
+	case "pgdn":
+		t.move(t.listHeight())
+	case "home":
+		t.move(-len(t.result.Bookmarks))
+	case "end":
+		t.move(len(t.result.Bookmarks))
+	case "enter":
+		if bm, ok := t.current(); ok {
+			t.status = "Opened " + bm.URL
+			if err := openURL(bm.URL); err != nil {
+				t.status = err.Error()
+			}
+		}
+	case "ctrl-y":
+		if bm, ok := t.current(); ok {
+			t.status = "Copied " + bm.URL
+			if err := copyText(bm.URL); err != nil {
+				t.status = err.Error()
+			}
+		}
+	case "ctrl-t":
+		t.editTags()
+	case "ctrl-d", "delete":
+		t.confirmDelete()
+	case "backspace":
+		if len(t.query) > 0 {
+			t.query = t.query[:len(t.query)-1]
+			t.search()
+		}
+	case "ctrl-u":
+		t.query = nil
+		t.search()
+	case "ctrl-w":
+		t.query = deleteWord(t.query)
+		t.search()
+	case "":
+		t.query = append(t.query, k.r)
+		t.selected = 0
+		t.search()
+	}
+}
+
+func (t *tui) handlePrompt(k key) {
+	p := t.prompt
+	switch k.name {
+	case "esc", "ctrl-c":
+		t.prompt = nil
+	case "enter":
+		t.prompt = nil
+		p.done(string(p.input))
+	case "backspace":
+		if len(p.input) > 0 {
+			p.input = p.input[:len(p.input)-1]
+		}
+	case "ctrl-u":
+		p.input = nil
+	case "ctrl-w":
+		p.input = deleteWord(p.input)
+	case "":
+		p.input = append(p.input, k.r)
+	}
+}
+
+func (t *tui) move(delta int) {
+	t.selected = clamp(t.selected+delta, 0, len(t.result.Bookmarks)-1)
+}
+
+// editTags prompts for the selected bookmark's tags as a comma-separated list
+func (t *tui) editTags() {
+	bm, ok := t.current()
+	if !ok {
+		return
+	}
+	t.prompt = &inlinePrompt{
+		label: "Tags (comma-separated): ",
+		input: []rune(strings.Join(bm.Tags, ", ")),
+		done: func(answer string) {
+			var tags []string
+			for _, tag := range strings.Split(answer, ",") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					tags = append(tags, tag)
+				}
+			}
+			bm.Tags = tags
+			bm.UpdatedAt = time.Now().Unix()
+			if err := t.st.Put(context.Background(), bm); err != nil {
+				t.status = fmt.Sprintf("Failed to save tags: %v", err)
+				return
+			}
+			t.search()
+			t.status = "Saved tags for " + bm.Title
+		},
+	}
+}
+
+// confirmDelete asks before removing the selected bookmark
+func (t *tui) confirmDelete() {
+	bm, ok := t.current()
+	if !ok {
+		return
+	}
+	t.prompt = &inlinePrompt{
+		label: fmt.Sprintf("Delete %q? [y/N] ", bm.Title),
+		done: func(answer string) {
+			if !strings.EqualFold(strings.TrimSpace(answer), "y") {
+				return
+			}
+			if err := t.st.Delete(context.Background(), bm.ID); err != nil {
+				t.status = fmt.Sprintf("Failed to delete: %v", err)
+				return
+			}
+			t.search()
+			t.status = "Deleted " + bm.Title
+		},
+	}
+}
+
+func (t *tui) size() (width, height int) {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 || height <= 0 {
+		return 80, 24
+	}
+	return width, height
+}
+
+// preview returns the preview pane height for the terminal height
+func (t *tui) preview() int {
+	_, height := t.size()
+	return clamp(previewHeight, 0, height/3)
+}
+
+// listHeight is the number of result rows: everything but the query line,
+// the info line, the separator, the preview and the help line
+func (t *tui) listHeight() int {
+	_, height := t.size()
+	return max(height-4-t.preview(), 1)
+}
+
+func (t *tui) render() {
+	width, _ := t.size()
+	rows := t.listHeight()
+
+	if t.selected < t.top {
+		t.top = t.selected
+	}
+	if t.selected >= t.top+rows {
+		t.top = t.selected - rows + 1
+	}
+	t.top = clamp(t.top, 0, max(len(t.result.Bookmarks)-rows, 0))
+
+	w := t.out
+	w.WriteString(ansiClear)
+	t.line("> "+string(t.query), width, "")
+
+	info := fmt.Sprintf("%d results", t.result.Total)
+	if t.result.Total > len(t.result.Bookmarks) {
+		info = fmt.Sprintf("%d of %d results", len(t.result.Bookmarks), t.result.Total)
+	}
+	if t.status != "" {
+		info += " · " + t.status
+	}
+	t.line(info, width, ansiDim)
+
+	for i := 0; i < rows; i++ {
+		n := t.top + i
+		if n >= len(t.result.Bookmarks) {
+			t.line("", width, "")
+			continue
+		}
+		bm := t.result.Bookmarks[n]
+		text := "  " + bm.Title
+		if bm.Title == "" {
+			text = "  " + bm.URL
+		}
+		if n == t.selected {
+			t.line("▌"+text[1:], width, ansiReverse)
+		} else {
+			t.line(text, width, "")
+		}
+	}
+
+	t.line(strings.Repeat("─", width), width, ansiDim)
+	preview := t.previewLines(width)
+	for i := 0; i < t.preview(); i++ {
+		text := ""
+		if i < len(preview) {
+			text = preview[i]
+		}
+		style := ""
+		if i == 0 {
+			style = ansiBold
+		}
+		t.line(text, width, style)
+	}
+
+	if t.prompt != nil {
+		w.WriteString(truncate(t.prompt.label+string(t.prompt.input), width))
+	} else {
+		w.WriteString(ansiDim + truncate(tuiHelp, width) + ansiReset)
+		// Park the cursor at the end of the query
+		fmt.Fprintf(w, "\x1b[1;%dH", min(3+len(t.query), width))
+	}
+	w.Flush()
+}
+
+// line writes one screen row, cut to width
+func (t *tui) line(text string, width int, style string) {
+	t.out.WriteString(style + truncate(text, width) + ansiReset + ansiClearLine + "\r\n")
+}
+
+// previewLines describes the selected bookmark for the preview pane
+func (t *tui) previewLines(width int) []string {
+	bm, ok := t.current()
+	if !ok {
+		return nil
+	}
+	lines := []string{bm.Title, bm.URL}
+	if len(bm.Tags) > 0 {
+		lines = append(lines, "Tags: "+strings.Join(bm.Tags, ", "))
+	}
+	if bm.Folder != "" {
+		lines = append(lines, "Folder: "+bm.Folder)
+	}
+	dates := "Created: " + time.Unix(bm.CreatedAt, 0).Format("2006-01-02")
+	if bm.UpdatedAt > 0 && bm.UpdatedAt != bm.CreatedAt {
+		dates += "  Updated: " + time.Unix(bm.UpdatedAt, 0).Format("2006-01-02")
+	}
+	lines = append(lines, dates)
+	if bm.Description != "" {
+		lines = append(lines, wrap(bm.Description, width)...)
+	}
+	return lines
+}
+
+// readKeys reads whatever is available on in and decodes it into keys
+func readKeys(in io.Reader) ([]key, error) {
+	buf := make([]byte, 256)
+	n, err := in.Read(buf)
+	if n == 0 && err != nil {
+		return nil, err
+	}
+	return decodeKeys(buf[:n]), nil
+}
+
+// escapeKeys maps the body of CSI and SS3 escape sequences to key names
+var escapeKeys = map[string]string{
+	"A": "up", "B": "down", "C": "right", "D": "left",
+	"H": "home", "F": "end", "1~": "home", "4~": "end",
+	"3~": "delete", "5~": "pgup", "6~": "pgdn",
+}
+
+func decodeKeys(b []byte) []key {
+	var keys []key
+	for len(b) > 0 {
+		switch c := b[0]; {
+		case c == 0x1b:
+			if len(b) > 2 && (b[1] == '[' || b[1] == 'O') {
+				end := 2
+				for end < len(b) && (b[end] < 0x40 || b[end] > 0x7e) {
+					end++
+				}
+				if end < len(b) {
+					if name, ok := escapeKeys[string(b[2:end+1])]; ok {
+						keys = append(keys, key{name: name})
+					}
+					b = b[end+1:]
+					continue
+				}
+			}
+			keys = append(keys, key{name: "esc"})
+			b = b[1:]
+		case c == '\r' || c == '\n':
+			keys = append(keys, key{name: "enter"})
+			b = b[1:]
+		case c == 0x7f || c == 0x08:
+			keys = append(keys, key{name: "backspace"})
+			b = b[1:]
+		case c < 0x20:
+			keys = append(keys, key{name: "ctrl-" + string(rune('a'+c-1))})
+			b = b[1:]
+		default:
+			r, size := utf8.DecodeRune(b)
+			if unicode.IsPrint(r) {
+				keys = append(keys, key{r: r})
+			}
+			b = b[size:]
+		}
+	}
+	return keys
+}
+
+// deleteWord removes the last word and any spaces after it
+func deleteWord(input []rune) []rune {
+	end := len(input)
+	for end > 0 && input[end-1] == ' ' {
+		end--
+	}
+	for end > 0 && input[end-1] != ' ' {
+		end--
+	}
+	return input[:end]
+}
+
+// truncate cuts s to width runes, replacing control characters with spaces
+func truncate(s string, width int) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range s {
+		if n >= width {
+			break
+		}
+		if unicode.IsControl(r) {
+			r = ' '
+		}
+		b.WriteRune(r)
+		n++
+	}
+	return b.String()
+}
+
+// wrap splits s into lines of at most width runes at word boundaries
+func wrap(s string, width int) []string {
+	var lines []string
+	var current []rune
+	for _, word := range strings.Fields(s) {
+		w := []rune(word)
+		if len(current) > 0 && len(current)+1+len(w) > width {
+			lines = append(lines, string(current))
+			current = nil
+		}
+		if len(current) > 0 {
+			current = append(current, ' ')
+		}
+		current = append(current, w...)
+	}
+	if len(current) > 0 {
+		lines = append(lines, string(current))
+	}
+	return lines
+}
+
+func clamp(n, lo, hi int) int {
+	if n > hi {
+		n = hi
+	}
+	if n < lo {
+		n = lo
+	}
+	return n
+}
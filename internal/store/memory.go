@@ -0,0 +1,271 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/abhijith/bookmark-cli/internal/index"
+	"github.com/abhijith/bookmark-cli/internal/models"
+	"github.com/abhijith/bookmark-cli/internal/urlnorm"
+)
+
+// MemoryStore keeps bookmarks in process memory; nothing survives a restart.
+// It is meant for tests and throwaway sessions.
+type MemoryStore struct {
+	mu        sync.RWMutex
+	bookmarks map[string]models.Bookmark
+	urls      map[string]int // bookmarks per canonical URL
+	postings  index.Postings
+	meta      map[string]string
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		bookmarks: make(map[string]models.Bookmark),
+		urls:      make(map[string]int),
+		postings:  make(index.Postings),
+		meta:      make(map[string]string),
+	}
+}
+
+func (s *MemoryStore) Put(ctx context.Context, bm models.Bookmark) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.put(bm)
+	return nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, id string) (models.Bookmark, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	bm, ok := s.bookmarks[id]
+	if !ok {
+		return models.Bookmark{}, ErrNotFound
+	}
+	return bm, nil
+}
+
+func (s *MemoryStore) IDsWithPrefix(ctx context.Context, prefix string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var ids []string
+	for id := range s.bookmarks {
+		if strings.HasPrefix(id, prefix) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.bookmarks[id]; !ok {
+		return ErrNotFound
+	}
+	s.remove(id)
+	return nil
+}
+
+func (s *MemoryStore) HasURL(ctx context.Context, url string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.urls[urlnorm.Canonical(url)] > 0, nil
+}
+
+func (s *MemoryStore) Count(ctx context.Context) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.bookmarks), nil
+}
+
+func (s *MemoryStore) List(ctx context.Context) ([]models.Bookmark, error) {
+	return s.Query(ctx, Query{})
+}
+
+func (s *MemoryStore) Query(ctx context.Context, q Query) ([]models.Bookmark, error) {
+	bookmarks := s.sorted()
+	if q.Reverse {
+		sortByCreatedAt(bookmarks, true)
+	}
+	if len(q.Terms) == 0 {
+		return q.filter(bookmarks), nil
+	}
+
+	s.mu.RLock()
+	ids := s.postings.Lookup(q.Terms)
+	s.mu.RUnlock()
+
+	var candidates []models.Bookmark
+	for _, bm := range bookmarks {
+		if ids[bm.ID] {
+			candidates = append(candidates, bm)
+		}
+	}
+	return q.filter(candidates), nil
+}
+
+func (s *MemoryStore) CountQuery(ctx context.Context, q Query) (int, error) {
+	q.Offset, q.Limit = 0, 0
+	matches, err := s.Query(ctx, q)
+	return len(matches), err
+}
+
+func (s *MemoryStore) DocFreq(ctx context.Context, tokens []string) (map[string]int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	freq := make(map[string]int, len(tokens))
+	for _, token := range tokens {
+		freq[token] = len(s.postings.Lookup([]string{token}))
+	}
+	return freq, nil
+}
+
+func (s *MemoryStore) Vocabulary(ctx context.Context, prefix string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var terms []string
+	for term := range s.postings {
+		if strings.HasPrefix(term, prefix) {
+			terms = append(terms, term)
+		}
+	}
+	sort.Strings(terms)
+	return terms, nil
+}
+
+func (s *MemoryStore) Tags(ctx context.Context) (map[string]int, error) {
+	return countTags(s.sorted()), nil
+}
+
+func (s *MemoryStore) Reindex(ctx context.Context, progress func(done, total int)) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.postings = make(index.Postings)
+	done := 0
+	for id, bm := range s.bookmarks {
+		s.postings.Add(id, index.Terms(bm))
+		done++
+		if progress != nil {
+			progress(done, len(s.bookmarks))
+		}
+	}
+	return done, nil
+}
+
+func (s *MemoryStore) Dedupe(ctx context.Context, strategy MergeStrategy) (int, error) {
+	merged, dupes := mergeDuplicates(s.sorted(), strategy)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range dupes {
+		s.remove(id)
+	}
+	for _, bm := range merged {
+		s.put(bm)
+	}
+	return len(dupes), nil
+}
+
+// Clean only has duplicates to merge: the maps and the postings are updated
+// together under one lock, so the indexes cannot drift
+func (s *MemoryStore) Clean(ctx context.Context, opts CleanOptions) (CleanReport, error) {
+	var report CleanReport
+	if opts.DryRun {
+		report.Duplicates = countDuplicates(s.sorted())
+		return report, nil
+	}
+	var err error
+	report.Duplicates, err = s.Dedupe(ctx, opts.Strategy)
+	return report, err
+}
+
+func (s *MemoryStore) Rekey(ctx context.Context, progress func(done, total int)) (int, error) {
+	bookmarks, merged, err := rekeyed(s.sorted())
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.bookmarks = make(map[string]models.Bookmark, len(bookmarks))
+	s.urls = make(map[string]int, len(bookmarks))
+	s.postings = make(index.Postings)
+	for i, bm := range bookmarks {
+		s.put(bm)
+		if progress != nil {
+			progress(i+1, len(bookmarks))
+		}
+	}
+	return merged, nil
+}
+
+func (s *MemoryStore) Meta(ctx context.Context, key string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.meta[key], nil
+}
+
+func (s *MemoryStore) SetMeta(ctx context.Context, key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.meta[key] = value
+	return nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// put stores bm, replacing the bookmark with its ID; s.mu must be held
+func (s *MemoryStore) put(bm models.Bookmark) {
+	if _, ok := s.bookmarks[bm.ID]; ok {
+		s.remove(bm.ID)
+	}
+	bm.Tags = append([]string(nil), bm.Tags...)
+	s.bookmarks[bm.ID] = bm
+	s.urls[urlnorm.Canonical(bm.URL)]++
+	s.postings.Add(bm.ID, index.Terms(bm))
+}
+
+// remove drops the bookmark with the given ID, which must exist; s.mu must
+// be held
+func (s *MemoryStore) remove(id string) {
+	bm := s.bookmarks[id]
+	delete(s.bookmarks, id)
+	canonical := urlnorm.Canonical(bm.URL)
+	if s.urls[canonical]--; s.urls[canonical] <= 0 {
+		delete(s.urls, canonical)
+	}
+	s.postings.Remove(id, index.Terms(bm))
+}
+
+// sorted returns a snapshot of all bookmarks ordered by CreatedAt
+func (s *MemoryStore) sorted() []models.Bookmark {
+	s.mu.RLock()
+	bookmarks := make([]models.Bookmark, 0, len(s.bookmarks))
+	for _, bm := range s.bookmarks {
+		bookmarks = append(bookmarks, bm)
+	}
+	s.mu.RUnlock()
+
+	sortByCreatedAt(bookmarks, false)
+	return bookmarks
+}
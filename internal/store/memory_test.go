@@ -0,0 +1,86 @@
+package store
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/abhijith/bookmark-cli/internal/models"
+)
+
+func TestMemoryStoreClean(t *testing.T) {
+	ctx := context.Background()
+	st := NewMemoryStore()
+	for _, bm := range []models.Bookmark{
+		{ID: "legacy", URL: "http://www.example.com/page/", Title: "Old", Tags: []string{"a"}, CreatedAt: 1},
+		{ID: NewID("https://example.com/page"), URL: "https://example.com/page", Title: "New", Tags: []string{"b"}, CreatedAt: 2},
+		{ID: NewID("https://example.com/other"), URL: "https://example.com/other", CreatedAt: 3},
+	} {
+		if err := st.Put(ctx, bm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := st.Clean(ctx, CleanOptions{Strategy: MergeUnion, DryRun: true})
+	if err != nil || report.Duplicates != 1 {
+		t.Fatalf("dry run = %+v, %v; want 1 duplicate", report, err)
+	}
+	if n, _ := st.Count(ctx); n != 3 {
+		t.Fatalf("dry run left %d bookmarks, want 3", n)
+	}
+
+	report, err = st.Clean(ctx, CleanOptions{Strategy: MergeUnion})
+	if err != nil || report.Duplicates != 1 {
+		t.Fatalf("Clean = %+v, %v; want 1 duplicate", report, err)
+	}
+	bm, err := st.Get(ctx, "legacy")
+	if err != nil {
+		t.Fatalf("oldest copy: %v", err)
+	}
+	if want := []string{"a", "b"}; !reflect.DeepEqual(bm.Tags, want) {
+		t.Errorf("merged tags = %v, want %v", bm.Tags, want)
+	}
+	if _, err := st.Get(ctx, NewID("https://example.com/page")); err != ErrNotFound {
+		t.Errorf("newer copy still stored: %v", err)
+	}
+	if ok, _ := st.HasURL(ctx, "https://example.com/page"); !ok {
+		t.Error("HasURL lost the merged URL")
+	}
+
+	if err := st.Delete(ctx, "legacy"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := st.HasURL(ctx, "https://example.com/page"); ok {
+		t.Error("HasURL still reports a deleted URL")
+	}
+}
+
+func TestMemoryStoreDedupeSkipKeepsTags(t *testing.T) {
+	ctx := context.Background()
+	st := NewMemoryStore()
+	for _, bm := range []models.Bookmark{
+		{ID: "legacy", URL: "https://example.com/page", Title: "Old", Tags: []string{"a"}, Sources: []string{"chrome"}, CreatedAt: 1},
+		{ID: "other", URL: "https://www.example.com/page/", Title: "New", Tags: []string{"b"}, Sources: []string{"firefox"}, CreatedAt: 2},
+	} {
+		if err := st.Put(ctx, bm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n, err := st.Dedupe(ctx, MergeSkip); err != nil || n != 1 {
+		t.Fatalf("Dedupe = %d, %v; want 1", n, err)
+	}
+	bm, err := st.Get(ctx, "legacy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bm.Title != "Old" {
+		t.Errorf("title = %q, want the stored one kept", bm.Title)
+	}
+	if want := []string{"a", "b"}; !reflect.DeepEqual(bm.Tags, want) {
+		t.Errorf("tags = %v, want %v", bm.Tags, want)
+	}
+	if want := []string{"chrome", "firefox"}; !reflect.DeepEqual(bm.Sources, want) {
+		t.Errorf("sources = %v, want %v", bm.Sources, want)
+	}
+}
@@ -0,0 +1,176 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/abhijith/bookmark-cli/internal/models"
+	"github.com/abhijith/bookmark-cli/internal/urlnorm"
+)
+
+// MergeStrategy decides how a duplicate is folded into the bookmark already stored
+type MergeStrategy string
+
+const (
+	// MergeUnion combines both copies: tags and sources are unioned, the
+	// earliest CreatedAt is kept, the longer title and description win and
+	// an empty folder is filled in
+	MergeUnion MergeStrategy = "union"
+	// MergeNewest is MergeUnion except that the incoming title, description
+	// and folder replace the stored ones whenever they are set
+	MergeNewest MergeStrategy = "newest"
+	// MergeSkip leaves the stored bookmark untouched
+	MergeSkip MergeStrategy = "skip"
+)
+
+// DefaultMergeStrategy returns the strategy named by BOOKMARK_MERGE (union,
+// newest or skip), MergeUnion when it is unset or unknown
+func DefaultMergeStrategy() MergeStrategy {
+	switch strategy := MergeStrategy(strings.ToLower(os.Getenv("BOOKMARK_MERGE"))); strategy {
+	case MergeNewest, MergeSkip:
+		return strategy
+	}
+	return MergeUnion
+}
+
+// UpsertResult says what Upsert did with a bookmark
+type UpsertResult int
+
+const (
+	// Added means the URL was new
+	Added UpsertResult = iota
+	// Merged means the bookmark was folded into the stored copy
+	Merged
+	// Unchanged means the stored copy already had everything
+	Unchanged
+)
+
+// Upsert stores bm or, when its canonical URL is already bookmarked, merges
+// it into the stored bookmark with strategy
+func Upsert(ctx context.Context, st Store, bm models.Bookmark, strategy MergeStrategy) (UpsertResult, error) {
+	bm.ID = NewID(bm.URL)
+	stored, err := st.Get(ctx, bm.ID)
+	if err == ErrNotFound {
+		// A bookmark stored under a legacy ID may still own the URL
+		stored, err = FindByURL(ctx, st, bm.URL)
+		if err == ErrNotFound {
+			return Added, st.Put(ctx, bm)
+		}
+	}
+	if err != nil {
+		return Unchanged, err
+	}
+	if err := checkCollision(stored, bm); err != nil {
+		return Unchanged, err
+	}
+
+	merged, changed := Merge(stored, bm, strategy)
+	if !changed {
+		return Unchanged, nil
+	}
+	merged.UpdatedAt = bm.UpdatedAt
+	return Merged, st.Put(ctx, merged)
+}
+
+// FindByURL returns the stored bookmark with the same canonical URL as url,
+// or ErrNotFound. It reads every bookmark, so it is only worth calling for
+// URLs that Get(NewID(url)) misses, like those kept under a legacy ID.
+func FindByURL(ctx context.Context, st Store, url string) (models.Bookmark, error) {
+	exists, err := st.HasURL(ctx, url)
+	if err != nil || !exists {
+		if err == nil {
+			err = ErrNotFound
+		}
+		return models.Bookmark{}, err
+	}
+	all, err := st.List(ctx)
+	if err != nil {
+		return models.Bookmark{}, err
+	}
+	canonical := urlnorm.Canonical(url)
+	for _, bm := range all {
+		if urlnorm.Canonical(bm.URL) == canonical {
+			return bm, nil
+		}
+	}
+	return models.Bookmark{}, ErrNotFound
+}
+
+// checkCollision fails if a and b share an ID without being the same page,
+// which 64-bit hashes make unlikely but not impossible
+func checkCollision(a, b models.Bookmark) error {
+	if urlnorm.Canonical(a.URL) != urlnorm.Canonical(b.URL) {
+		return fmt.Errorf("ID collision: %s and %s both hash to %s", a.URL, b.URL, NewID(b.URL))
+	}
+	return nil
+}
+
+// Merge folds incoming into stored, a bookmark of the same page, and reports
+// whether stored changed. The result keeps the stored ID and URL.
+func Merge(stored, incoming models.Bookmark, strategy MergeStrategy) (models.Bookmark, bool) {
+	if strategy == MergeSkip {
+		return stored, false
+	}
+
+	merged := stored
+	if incoming.CreatedAt != 0 && (merged.CreatedAt == 0 || incoming.CreatedAt < merged.CreatedAt) {
+		merged.CreatedAt = incoming.CreatedAt
+	}
+	merged.Tags = cleanTags(append(append([]string(nil), stored.Tags...), incoming.Tags...))
+	merged.Sources = cleanTags(append(append([]string(nil), stored.Sources...), incoming.Sources...))
+
+	if strategy == MergeNewest {
+		merged.Title = prefer(realTitle(incoming), stored.Title)
+		merged.Description = prefer(incoming.Description, stored.Description)
+		merged.Folder = prefer(incoming.Folder, stored.Folder)
+	} else {
+		merged.Title = prefer(longer(realTitle(stored), realTitle(incoming)), stored.Title)
+		merged.Description = longer(stored.Description, incoming.Description)
+		merged.Folder = prefer(stored.Folder, incoming.Folder)
+	}
+
+	changed := merged.CreatedAt != stored.CreatedAt || merged.Title != stored.Title ||
+		merged.Description != stored.Description || merged.Folder != stored.Folder ||
+		len(merged.Tags) != len(cleanTags(stored.Tags)) || len(merged.Sources) != len(cleanTags(stored.Sources))
+	return merged, changed
+}
+
+// Absorb folds dupe, a copy of stored that is about to be deleted, into
+// stored. Unlike Merge it unions the tags and sources even with MergeSkip, so
+// deleting the copy loses none of them.
+func Absorb(stored, dupe models.Bookmark, strategy MergeStrategy) models.Bookmark {
+	if strategy != MergeSkip {
+		merged, _ := Merge(stored, dupe, strategy)
+		return merged
+	}
+	stored.Tags = cleanTags(append(append([]string(nil), stored.Tags...), dupe.Tags...))
+	stored.Sources = cleanTags(append(append([]string(nil), stored.Sources...), dupe.Sources...))
+	return stored
+}
+
+// realTitle returns bm's title, or "" when it is only the URL standing in
+// for a missing one
+func realTitle(bm models.Bookmark) string {
+	if bm.Title == bm.URL {
+		return ""
+	}
+	return bm.Title
+}
+
+// prefer returns a unless it is empty
+func prefer(a, b string) string {
+	if strings.TrimSpace(a) != "" {
+		return a
+	}
+	return b
+}
+
+// longer returns the longer of a and b, a on a tie
+func longer(a, b string) string {
+	if len(strings.TrimSpace(b)) > len(strings.TrimSpace(a)) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,964 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/abhijith/bookmark-cli/internal/index"
+	"github.com/abhijith/bookmark-cli/internal/models"
+	"github.com/abhijith/bookmark-cli/internal/urlnorm"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	RedisBookmarksKey = "bookmarks:index"
+	RedisURLSetKey    = "bookmarks:urls"
+	RedisTermsKey     = "bookmarks:terms"
+	RedisTagsKey      = "bookmarks:tags"
+	RedisMetaPrefix   = "bookmarks:"
+	RedisBookmarkKey  = "bookmark:"
+	RedisTermKey      = "term:"
+	RedisTagKey       = "tag:"
+
+	// redisQueryBatch is how many IDs Query loads per round trip
+	redisQueryBatch = 500
+
+	// RedisTitleSetKey is the unused title vocabulary of older versions
+	RedisTitleSetKey = "bookmarks:titles"
+)
+
+// globEscaper quotes the SCAN pattern wildcards in a literal prefix
+var globEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+
+// RedisStore keeps each bookmark in a bookmark:<id> hash. The bookmarks:index
+// sorted set holds the IDs scored by CreatedAt and bookmarks:urls the known URLs.
+// Every index term has a term:<term> set of IDs, and bookmarks:terms lists the
+// terms (all scored 0) so prefixes can be expanded with ZRANGEBYLEX. Tags
+// work the same way: a tag:<name> set of IDs per tag and bookmarks:tags
+// listing the names.
+type RedisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore wraps an existing Redis client
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+// Client exposes the underlying Redis client
+func (s *RedisStore) Client() *redis.Client {
+	return s.client
+}
+
+func (s *RedisStore) Put(ctx context.Context, bm models.Bookmark) error {
+	old, err := s.Get(ctx, bm.ID)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+	exists := err == nil
+	removed, added := index.Diff(index.Terms(old), index.Terms(bm))
+	untagged, tagged := index.Diff(cleanTags(old.Tags), cleanTags(bm.Tags))
+
+	// Drop the URL of the previous version if the edit changed it and no
+	// other bookmark has it
+	release := false
+	if exists && urlnorm.Canonical(old.URL) != urlnorm.Canonical(bm.URL) {
+		shared, err := s.sharesURL(ctx, bm.ID, old.URL)
+		if err != nil {
+			return err
+		}
+		release = !shared
+	}
+
+	pipe := s.client.TxPipeline()
+	if release {
+		pipe.SRem(ctx, RedisURLSetKey, urlnorm.Canonical(old.URL))
+	}
+	pipe.Del(ctx, bookmarkKey(bm.ID))
+	pipe.HSet(ctx, bookmarkKey(bm.ID), toHash(bm))
+	pipe.ZAdd(ctx, RedisBookmarksKey, &redis.Z{
+		Score:  float64(bm.CreatedAt),
+		Member: bm.ID,
+	})
+	pipe.SAdd(ctx, RedisURLSetKey, urlnorm.Canonical(bm.URL))
+	s.unindex(ctx, pipe, bm.ID, removed)
+	s.index(ctx, pipe, bm.ID, added)
+	s.untag(ctx, pipe, bm.ID, untagged)
+	s.tag(ctx, pipe, bm.ID, tagged)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	if err := s.pruneTerms(ctx, removed); err != nil {
+		return err
+	}
+	return s.pruneTags(ctx, untagged)
+}
+
+func (s *RedisStore) Get(ctx context.Context, id string) (models.Bookmark, error) {
+	fields, err := s.client.HGetAll(ctx, bookmarkKey(id)).Result()
+	if err != nil {
+		return models.Bookmark{}, err
+	}
+	if len(fields) == 0 {
+		return models.Bookmark{}, ErrNotFound
+	}
+	return fromHash(fields), nil
+}
+
+func (s *RedisStore) IDsWithPrefix(ctx context.Context, prefix string) ([]string, error) {
+	keys, err := s.scanKeys(ctx, RedisBookmarkKey+globEscaper.Replace(prefix)+"*")
+	if err != nil {
+		return nil, err
+	}
+	for i, key := range keys {
+		keys[i] = strings.TrimPrefix(key, RedisBookmarkKey)
+	}
+	return keys, nil
+}
+
+func (s *RedisStore) Delete(ctx context.Context, id string) error {
+	bm, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	shared, err := s.sharesURL(ctx, id, bm.URL)
+	if err != nil {
+		return err
+	}
+	return s.remove(ctx, bm, !shared)
+}
+
+// remove deletes bm and its index entries, and its URL too if releaseURL is
+// set. Callers that know another bookmark keeps the URL skip the scan Delete
+// needs to find out.
+func (s *RedisStore) remove(ctx context.Context, bm models.Bookmark, releaseURL bool) error {
+	terms := index.Terms(bm)
+	tags := cleanTags(bm.Tags)
+
+	pipe := s.client.TxPipeline()
+	pipe.Del(ctx, bookmarkKey(bm.ID))
+	pipe.ZRem(ctx, RedisBookmarksKey, bm.ID)
+	if releaseURL {
+		pipe.SRem(ctx, RedisURLSetKey, urlnorm.Canonical(bm.URL))
+	}
+	s.unindex(ctx, pipe, bm.ID, terms)
+	s.untag(ctx, pipe, bm.ID, tags)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	if err := s.pruneTerms(ctx, terms); err != nil {
+		return err
+	}
+	return s.pruneTags(ctx, tags)
+}
+
+// sharesURL reports whether a bookmark other than id has the canonical URL
+// of url, which happens while copies under legacy IDs are left
+func (s *RedisStore) sharesURL(ctx context.Context, id, url string) (bool, error) {
+	bookmarks, err := s.List(ctx)
+	if err != nil {
+		return false, err
+	}
+	canonical := urlnorm.Canonical(url)
+	for _, bm := range bookmarks {
+		if bm.ID != id && urlnorm.Canonical(bm.URL) == canonical {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *RedisStore) HasURL(ctx context.Context, url string) (bool, error) {
+	return s.client.SIsMember(ctx, RedisURLSetKey, urlnorm.Canonical(url)).Result()
+}
+
+func (s *RedisStore) Count(ctx context.Context) (int, error) {
+	n, err := s.client.ZCard(ctx, RedisBookmarksKey).Result()
+	return int(n), err
+}
+
+func (s *RedisStore) List(ctx context.Context) ([]models.Bookmark, error) {
+	return s.Query(ctx, Query{})
+}
+
+func (s *RedisStore) Query(ctx context.Context, q Query) ([]models.Bookmark, error) {
+	if len(q.Terms) > 0 || len(q.Tags) > 0 {
+		ids, err := s.lookup(ctx, q.Terms, q.Tags)
+		if err != nil {
+			return nil, err
+		}
+		bookmarks, err := s.load(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		sortByCreatedAt(bookmarks, q.Reverse)
+		return q.filter(bookmarks), nil
+	}
+
+	min, max := scoreRange(q)
+
+	// Walk the index in result order one batch at a time so a page near the
+	// top never loads the whole collection
+	c := collector{q: q}
+	for offset := int64(0); ; offset += redisQueryBatch {
+		by := &redis.ZRangeBy{Min: min, Max: max, Offset: offset, Count: redisQueryBatch}
+		var ids []string
+		var err error
+		if q.Reverse {
+			ids, err = s.client.ZRevRangeByScore(ctx, RedisBookmarksKey, by).Result()
+		} else {
+			ids, err = s.client.ZRangeByScore(ctx, RedisBookmarksKey, by).Result()
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			break
+		}
+
+		bookmarks, err := s.load(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, bm := range bookmarks {
+			if c.add(bm) {
+				return c.matches, nil
+			}
+		}
+	}
+	return c.matches, nil
+}
+
+func (s *RedisStore) CountQuery(ctx context.Context, q Query) (int, error) {
+	if q.Match == nil && len(q.Terms) == 0 && len(q.Tags) == 0 {
+		min, max := scoreRange(q)
+		n, err := s.client.ZCount(ctx, RedisBookmarksKey, min, max).Result()
+		return int(n), err
+	}
+	if q.Match == nil && q.From == nil && q.To == nil {
+		ids, err := s.lookup(ctx, q.Terms, q.Tags)
+		return len(ids), err
+	}
+
+	q.Offset, q.Limit = 0, 0
+	matches, err := s.Query(ctx, q)
+	return len(matches), err
+}
+
+func (s *RedisStore) DocFreq(ctx context.Context, tokens []string) (map[string]int, error) {
+	freq := make(map[string]int, len(tokens))
+	for _, token := range tokens {
+		terms, err := s.expand(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+
+		switch len(terms) {
+		case 0:
+			freq[token] = 0
+		case 1:
+			n, err := s.client.SCard(ctx, termKey(terms[0])).Result()
+			if err != nil {
+				return nil, err
+			}
+			freq[token] = int(n)
+		default:
+			union := fmt.Sprintf("bookmarks:lookup:%d:df", os.Getpid())
+			n, err := s.client.SUnionStore(ctx, union, termKeys(terms)...).Result()
+			s.client.Del(ctx, union)
+			if err != nil {
+				return nil, err
+			}
+			freq[token] = int(n)
+		}
+	}
+	return freq, nil
+}
+
+func (s *RedisStore) Vocabulary(ctx context.Context, prefix string) ([]string, error) {
+	return s.expand(ctx, prefix)
+}
+
+func (s *RedisStore) Tags(ctx context.Context) (map[string]int, error) {
+	names, err := s.client.SMembers(ctx, RedisTagsKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	pipe := s.client.Pipeline()
+	cards := make([]*redis.IntCmd, len(names))
+	for i, name := range names {
+		cards[i] = pipe.SCard(ctx, tagKey(name))
+	}
+	if len(names) > 0 {
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	counts := make(map[string]int, len(names))
+	for i, name := range names {
+		if n := int(cards[i].Val()); n > 0 {
+			counts[name] = n
+		}
+	}
+	return counts, nil
+}
+
+func (s *RedisStore) Reindex(ctx context.Context, progress func(done, total int)) (int, error) {
+	// Drop every posting list, tag set and both name lists, including the
+	// legacy title set
+	stale := []string{RedisTermsKey, RedisTagsKey, RedisTitleSetKey}
+	for _, pattern := range []string{RedisTermKey + "*", RedisTagKey + "*"} {
+		iter := s.client.Scan(ctx, 0, pattern, 1000).Iterator()
+		for iter.Next(ctx) {
+			stale = append(stale, iter.Val())
+		}
+		if err := iter.Err(); err != nil {
+			return 0, err
+		}
+	}
+	for start := 0; start < len(stale); start += 1000 {
+		end := start + 1000
+		if end > len(stale) {
+			end = len(stale)
+		}
+		if err := s.client.Del(ctx, stale[start:end]...).Err(); err != nil {
+			return 0, err
+		}
+	}
+
+	bookmarks, err := s.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for i, bm := range bookmarks {
+		pipe := s.client.Pipeline()
+		s.index(ctx, pipe, bm.ID, index.Terms(bm))
+		s.tag(ctx, pipe, bm.ID, cleanTags(bm.Tags))
+		if _, err := pipe.Exec(ctx); err != nil {
+			return i, err
+		}
+		if progress != nil {
+			progress(i+1, len(bookmarks))
+		}
+	}
+	return len(bookmarks), nil
+}
+
+func (s *RedisStore) Dedupe(ctx context.Context, strategy MergeStrategy) (int, error) {
+	bookmarks, err := s.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	merged, dupes := mergeDuplicates(bookmarks, strategy)
+	byID := make(map[string]models.Bookmark, len(bookmarks))
+	for _, bm := range bookmarks {
+		byID[bm.ID] = bm
+	}
+
+	// Save the merged copies before deleting anything so an interrupted
+	// run loses no tags. Each duplicate's URL stays with its survivor.
+	for _, bm := range merged {
+		if err := s.Put(ctx, bm); err != nil {
+			return 0, err
+		}
+	}
+	for _, id := range dupes {
+		if err := s.remove(ctx, byID[id], false); err != nil {
+			return 0, err
+		}
+	}
+	return len(dupes), nil
+}
+
+func (s *RedisStore) Clean(ctx context.Context, opts CleanOptions) (CleanReport, error) {
+	var report CleanReport
+
+	// The index and the bookmark hashes must list the same IDs
+	members, err := s.client.ZRange(ctx, RedisBookmarksKey, 0, -1).Result()
+	if err != nil {
+		return report, err
+	}
+	indexed := make(map[string]bool, len(members))
+	for _, member := range members {
+		if !isLegacyMember(member) {
+			indexed[member] = true
+		}
+	}
+	keys, err := s.scanKeys(ctx, RedisBookmarkKey+"*")
+	if err != nil {
+		return report, err
+	}
+	for i, key := range keys {
+		keys[i] = strings.TrimPrefix(key, RedisBookmarkKey)
+	}
+	bookmarks, err := s.load(ctx, keys)
+	if err != nil {
+		return report, err
+	}
+
+	stored := make(map[string]bool, len(bookmarks))
+	var unindexed []*redis.Z
+	for _, bm := range bookmarks {
+		stored[bm.ID] = true
+		if !indexed[bm.ID] {
+			unindexed = append(unindexed, &redis.Z{Score: float64(bm.CreatedAt), Member: bm.ID})
+		}
+	}
+	var missing []interface{}
+	for id := range indexed {
+		if !stored[id] {
+			missing = append(missing, id)
+		}
+	}
+	report.MissingBookmarks, report.Unindexed = len(missing), len(unindexed)
+	if !opts.DryRun && (len(missing) > 0 || len(unindexed) > 0) {
+		pipe := s.client.TxPipeline()
+		if len(missing) > 0 {
+			pipe.ZRem(ctx, RedisBookmarksKey, missing...)
+		}
+		if len(unindexed) > 0 {
+			pipe.ZAdd(ctx, RedisBookmarksKey, unindexed...)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return report, err
+		}
+	}
+
+	if opts.DryRun {
+		report.Duplicates = countDuplicates(bookmarks)
+	} else {
+		if report.Duplicates, err = s.Dedupe(ctx, opts.Strategy); err != nil {
+			return report, err
+		}
+		if report.Duplicates > 0 {
+			if bookmarks, err = s.List(ctx); err != nil {
+				return report, err
+			}
+		}
+	}
+
+	// The URL set must hold exactly the canonical URLs of the bookmarks
+	urls, err := s.client.SMembers(ctx, RedisURLSetKey).Result()
+	if err != nil {
+		return report, err
+	}
+	want := make(map[string]bool, len(bookmarks))
+	for _, bm := range bookmarks {
+		want[urlnorm.Canonical(bm.URL)] = true
+	}
+	have := make(map[string]bool, len(urls))
+	var stale, absent []interface{}
+	for _, u := range urls {
+		have[u] = true
+		if !want[u] {
+			stale = append(stale, u)
+		}
+	}
+	for u := range want {
+		if !have[u] {
+			absent = append(absent, u)
+		}
+	}
+	report.StaleURLs, report.MissingURLs = len(stale), len(absent)
+	if !opts.DryRun && (len(stale) > 0 || len(absent) > 0) {
+		pipe := s.client.TxPipeline()
+		if len(stale) > 0 {
+			pipe.SRem(ctx, RedisURLSetKey, stale...)
+		}
+		if len(absent) > 0 {
+			pipe.SAdd(ctx, RedisURLSetKey, absent...)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return report, err
+		}
+	}
+
+	// Term and tag entries must point at bookmarks that still have them
+	terms := make(map[string]map[string]bool, len(bookmarks))
+	tags := make(map[string]map[string]bool, len(bookmarks))
+	for _, bm := range bookmarks {
+		terms[bm.ID] = make(map[string]bool)
+		for _, term := range index.Terms(bm) {
+			terms[bm.ID][term] = true
+		}
+		tags[bm.ID] = make(map[string]bool)
+		for _, tag := range cleanTags(bm.Tags) {
+			tags[bm.ID][tag] = true
+		}
+	}
+
+	vocabulary, err := s.client.ZRange(ctx, RedisTermsKey, 0, -1).Result()
+	if err != nil {
+		return report, err
+	}
+	report.OrphanTerms, err = s.cleanPostings(ctx, RedisTermKey, vocabulary, terms, opts.DryRun,
+		func(empty []interface{}) error {
+			return s.client.ZRem(ctx, RedisTermsKey, empty...).Err()
+		})
+	if err != nil {
+		return report, err
+	}
+
+	names, err := s.client.SMembers(ctx, RedisTagsKey).Result()
+	if err != nil {
+		return report, err
+	}
+	report.OrphanTags, err = s.cleanPostings(ctx, RedisTagKey, names, tags, opts.DryRun,
+		func(empty []interface{}) error {
+			return s.client.SRem(ctx, RedisTagsKey, empty...).Err()
+		})
+	return report, err
+}
+
+func (s *RedisStore) Rekey(ctx context.Context, progress func(done, total int)) (int, error) {
+	bookmarks, err := s.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	rekeyed, merged, err := rekeyed(bookmarks)
+	if err != nil {
+		return 0, err
+	}
+
+	// Write the new keys before removing the old ones so an interrupted
+	// run leaves duplicates behind rather than losing bookmarks. Every old
+	// key's URL lives on under its new one.
+	keep := make(map[string]bool, len(rekeyed))
+	for i, bm := range rekeyed {
+		if err := s.Put(ctx, bm); err != nil {
+			return 0, err
+		}
+		keep[bm.ID] = true
+		if progress != nil {
+			progress(i+1, len(rekeyed))
+		}
+	}
+	for _, bm := range bookmarks {
+		if keep[bm.ID] {
+			continue
+		}
+		if err := s.remove(ctx, bm, false); err != nil {
+			return 0, err
+		}
+	}
+	return merged, nil
+}
+
+func (s *RedisStore) Meta(ctx context.Context, key string) (string, error) {
+	value, err := s.client.Get(ctx, RedisMetaPrefix+key).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return value, err
+}
+
+func (s *RedisStore) SetMeta(ctx context.Context, key, value string) error {
+	return s.client.Set(ctx, RedisMetaPrefix+key, value, 0).Err()
+}
+
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
+
+// MigrateToHashes converts an index that still holds whole JSON bookmarks as
+// sorted-set members into the hash layout, in place. IDs are re-derived from
+// the URL because older browser imports gave unrelated URLs the same ID.
+// Members sharing a URL are merged into one bookmark with MergeUnion.
+// It returns the number of bookmarks converted.
+func (s *RedisStore) MigrateToHashes(ctx context.Context, progress func(done, total int)) (int, error) {
+	members, err := s.client.ZRangeWithScores(ctx, RedisBookmarksKey, 0, -1).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	var legacy []redis.Z
+	for _, z := range members {
+		if member, ok := z.Member.(string); ok && isLegacyMember(member) {
+			legacy = append(legacy, z)
+		}
+	}
+
+	converted := 0
+	for i, z := range legacy {
+		member := z.Member.(string)
+		pipe := s.client.TxPipeline()
+		pipe.ZRem(ctx, RedisBookmarksKey, member)
+
+		var bm models.Bookmark
+		if err := json.Unmarshal([]byte(member), &bm); err == nil && bm.URL != "" {
+			bm.ID = NewID(bm.URL)
+			// Legacy members of one URL would otherwise overwrite each other
+			stored, err := s.Get(ctx, bm.ID)
+			switch {
+			case err == nil:
+				if err := checkCollision(stored, bm); err != nil {
+					return converted, err
+				}
+				bm, _ = Merge(stored, bm, MergeUnion)
+			case err != ErrNotFound:
+				return converted, err
+			}
+			pipe.HSet(ctx, bookmarkKey(bm.ID), toHash(bm))
+			pipe.ZAdd(ctx, RedisBookmarksKey, &redis.Z{
+				Score:  float64(bm.CreatedAt),
+				Member: bm.ID,
+			})
+			pipe.SAdd(ctx, RedisURLSetKey, urlnorm.Canonical(bm.URL))
+			converted++
+		}
+
+		if _, err := pipe.Exec(ctx); err != nil {
+			return converted, err
+		}
+		if progress != nil {
+			progress(i+1, len(legacy))
+		}
+	}
+
+	return converted, nil
+}
+
+// CountLegacy returns how many index members still use the JSON layout
+func (s *RedisStore) CountLegacy(ctx context.Context) (int, error) {
+	members, err := s.client.ZRange(ctx, RedisBookmarksKey, 0, -1).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, member := range members {
+		if isLegacyMember(member) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// scoreRange turns the CreatedAt bounds of q into a bookmarks:index score range
+func scoreRange(q Query) (min, max string) {
+	min, max = "-inf", "+inf"
+	if q.From != nil {
+		min = strconv.FormatInt(*q.From, 10)
+	}
+	if q.To != nil {
+		max = strconv.FormatInt(*q.To, 10)
+	}
+	return min, max
+}
+
+// index adds id to the postings of terms
+func (s *RedisStore) index(ctx context.Context, pipe redis.Pipeliner, id string, terms []string) {
+	for _, term := range terms {
+		pipe.SAdd(ctx, termKey(term), id)
+		pipe.ZAdd(ctx, RedisTermsKey, &redis.Z{Member: term})
+	}
+}
+
+// unindex removes id from the postings of terms
+func (s *RedisStore) unindex(ctx context.Context, pipe redis.Pipeliner, id string, terms []string) {
+	for _, term := range terms {
+		pipe.SRem(ctx, termKey(term), id)
+	}
+}
+
+// pruneTerms drops terms whose postings became empty from the vocabulary
+func (s *RedisStore) pruneTerms(ctx context.Context, terms []string) error {
+	if len(terms) == 0 {
+		return nil
+	}
+
+	pipe := s.client.Pipeline()
+	cards := make([]*redis.IntCmd, len(terms))
+	for i, term := range terms {
+		cards[i] = pipe.SCard(ctx, termKey(term))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	var empty []interface{}
+	for i, card := range cards {
+		if card.Val() == 0 {
+			empty = append(empty, terms[i])
+		}
+	}
+	if len(empty) == 0 {
+		return nil
+	}
+	return s.client.ZRem(ctx, RedisTermsKey, empty...).Err()
+}
+
+// tag adds id to the sets of tags
+func (s *RedisStore) tag(ctx context.Context, pipe redis.Pipeliner, id string, tags []string) {
+	for _, tag := range tags {
+		pipe.SAdd(ctx, tagKey(tag), id)
+		pipe.SAdd(ctx, RedisTagsKey, tag)
+	}
+}
+
+// untag removes id from the sets of tags
+func (s *RedisStore) untag(ctx context.Context, pipe redis.Pipeliner, id string, tags []string) {
+	for _, tag := range tags {
+		pipe.SRem(ctx, tagKey(tag), id)
+	}
+}
+
+// pruneTags drops tags that no bookmark carries any more from bookmarks:tags
+func (s *RedisStore) pruneTags(ctx context.Context, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	pipe := s.client.Pipeline()
+	cards := make([]*redis.IntCmd, len(tags))
+	for i, tag := range tags {
+		cards[i] = pipe.SCard(ctx, tagKey(tag))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	var empty []interface{}
+	for i, card := range cards {
+		if card.Val() == 0 {
+			empty = append(empty, tags[i])
+		}
+	}
+	if len(empty) == 0 {
+		return nil
+	}
+	return s.client.SRem(ctx, RedisTagsKey, empty...).Err()
+}
+
+// lookup returns the IDs whose terms cover every token and that carry every
+// tag. Each token is expanded to the indexed terms it prefixes and each tag
+// to the stored names it matches (itself and its children, ignoring case);
+// the sets are unioned per token or tag and intersected across them on the
+// server.
+func (s *RedisStore) lookup(ctx context.Context, tokens, tags []string) ([]string, error) {
+	var groups [][]string
+	for _, token := range tokens {
+		terms, err := s.expand(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, termKeys(terms))
+	}
+	if len(tags) > 0 {
+		names, err := s.client.SMembers(ctx, RedisTagsKey).Result()
+		if err != nil {
+			return nil, err
+		}
+		for _, tag := range tags {
+			var keys []string
+			for _, name := range names {
+				if TagMatches(name, tag) {
+					keys = append(keys, tagKey(name))
+				}
+			}
+			groups = append(groups, keys)
+		}
+	}
+
+	var keys, scratch []string
+	defer func() {
+		if len(scratch) > 0 {
+			s.client.Del(ctx, scratch...)
+		}
+	}()
+	for _, group := range groups {
+		switch len(group) {
+		case 0:
+			return nil, nil
+		case 1:
+			keys = append(keys, group[0])
+			continue
+		}
+		union := fmt.Sprintf("bookmarks:lookup:%d:%d", os.Getpid(), len(scratch))
+		if err := s.client.SUnionStore(ctx, union, group...).Err(); err != nil {
+			return nil, err
+		}
+		scratch = append(scratch, union)
+		keys = append(keys, union)
+	}
+
+	return s.client.SInter(ctx, keys...).Result()
+}
+
+// expand returns the indexed terms that start with token
+func (s *RedisStore) expand(ctx context.Context, token string) ([]string, error) {
+	if token == "" {
+		return s.client.ZRange(ctx, RedisTermsKey, 0, -1).Result()
+	}
+	return s.client.ZRangeByLex(ctx, RedisTermsKey, &redis.ZRangeBy{
+		Min: "[" + token,
+		Max: "[" + token + "\xff",
+	}).Result()
+}
+
+// load fetches the hashes for ids in one round trip, keeping their order
+func (s *RedisStore) load(ctx context.Context, ids []string) ([]models.Bookmark, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	pipe := s.client.Pipeline()
+	cmds := make([]*redis.StringStringMapCmd, len(ids))
+	for i, id := range ids {
+		cmds[i] = pipe.HGetAll(ctx, bookmarkKey(id))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	bookmarks := make([]models.Bookmark, 0, len(ids))
+	for _, cmd := range cmds {
+		fields, err := cmd.Result()
+		if err != nil || len(fields) == 0 {
+			continue // Dangling index entry
+		}
+		bookmarks = append(bookmarks, fromHash(fields))
+	}
+	return bookmarks, nil
+}
+
+// cleanPostings removes the members of the prefix sets that want does not
+// list for their name (want maps ID to names), then drops names left without
+// members. It returns how many entries and names were (or would be) removed.
+func (s *RedisStore) cleanPostings(ctx context.Context, prefix string, names []string, want map[string]map[string]bool, dryRun bool, drop func(empty []interface{}) error) (int, error) {
+	keys, err := s.scanKeys(ctx, prefix+"*")
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	used := make(map[string]bool)
+	for start := 0; start < len(keys); start += 1000 {
+		end := start + 1000
+		if end > len(keys) {
+			end = len(keys)
+		}
+		batch := keys[start:end]
+
+		pipe := s.client.Pipeline()
+		cmds := make([]*redis.StringSliceCmd, len(batch))
+		for i, key := range batch {
+			cmds[i] = pipe.SMembers(ctx, key)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return removed, err
+		}
+
+		fix := s.client.Pipeline()
+		for i, cmd := range cmds {
+			name := strings.TrimPrefix(batch[i], prefix)
+			var orphans []interface{}
+			for _, id := range cmd.Val() {
+				if want[id][name] {
+					used[name] = true
+				} else {
+					orphans = append(orphans, id)
+				}
+			}
+			removed += len(orphans)
+			if len(orphans) > 0 {
+				fix.SRem(ctx, batch[i], orphans...)
+			}
+		}
+		if !dryRun && fix.Len() > 0 {
+			if _, err := fix.Exec(ctx); err != nil {
+				return removed, err
+			}
+		}
+	}
+
+	var empty []interface{}
+	for _, name := range names {
+		if !used[name] {
+			empty = append(empty, name)
+		}
+	}
+	removed += len(empty)
+	if dryRun || len(empty) == 0 {
+		return removed, nil
+	}
+	return removed, drop(empty)
+}
+
+// scanKeys returns every key matching pattern
+func (s *RedisStore) scanKeys(ctx context.Context, pattern string) ([]string, error) {
+	var keys []string
+	iter := s.client.Scan(ctx, 0, pattern, 1000).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	return keys, iter.Err()
+}
+
+func bookmarkKey(id string) string {
+	return RedisBookmarkKey + id
+}
+
+func termKey(term string) string {
+	return RedisTermKey + term
+}
+
+func tagKey(tag string) string {
+	return RedisTagKey + tag
+}
+
+func termKeys(terms []string) []string {
+	keys := make([]string, len(terms))
+	for i, term := range terms {
+		keys[i] = termKey(term)
+	}
+	return keys
+}
+
+// isLegacyMember reports whether an index member is a JSON bookmark rather than an ID
+func isLegacyMember(member string) bool {
+	return strings.HasPrefix(member, "{")
+}
+
+// toHash flattens a bookmark into hash fields; tags and sources are stored
+// as JSON arrays
+func toHash(bm models.Bookmark) map[string]interface{} {
+	tags, _ := json.Marshal(bm.Tags)
+	sources, _ := json.Marshal(bm.Sources)
+	return map[string]interface{}{
+		"id":          bm.ID,
+		"url":         bm.URL,
+		"title":       bm.Title,
+		"description": bm.Description,
+		"tags":        string(tags),
+		"folder":      bm.Folder,
+		"sources":     string(sources),
+		"created_at":  bm.CreatedAt,
+		"updated_at":  bm.UpdatedAt,
+	}
+}
+
+// fromHash is the inverse of toHash
+func fromHash(fields map[string]string) models.Bookmark {
+	bm := models.Bookmark{
+		ID:          fields["id"],
+		URL:         fields["url"],
+		Title:       fields["title"],
+		Description: fields["description"],
+		Folder:      fields["folder"],
+	}
+	bm.CreatedAt, _ = strconv.ParseInt(fields["created_at"], 10, 64)
+	bm.UpdatedAt, _ = strconv.ParseInt(fields["updated_at"], 10, 64)
+	json.Unmarshal([]byte(fields["tags"]), &bm.Tags)
+	json.Unmarshal([]byte(fields["sources"]), &bm.Sources)
+	return bm
+}
@@ -0,0 +1,475 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/abhijith/bookmark-cli/internal/index"
+	"github.com/abhijith/bookmark-cli/internal/models"
+	"github.com/abhijith/bookmark-cli/internal/urlnorm"
+	_ "modernc.org/sqlite"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS bookmarks (
+	id         TEXT PRIMARY KEY,
+	url        TEXT NOT NULL UNIQUE,
+	created_at INTEGER NOT NULL,
+	data       TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS bookmarks_created_at ON bookmarks(created_at);
+CREATE TABLE IF NOT EXISTS bookmark_tags (
+	bookmark_id TEXT NOT NULL REFERENCES bookmarks(id) ON DELETE CASCADE,
+	tag         TEXT NOT NULL,
+	PRIMARY KEY (bookmark_id, tag)
+);
+CREATE TABLE IF NOT EXISTS bookmark_terms (
+	term        TEXT NOT NULL,
+	bookmark_id TEXT NOT NULL REFERENCES bookmarks(id) ON DELETE CASCADE,
+	PRIMARY KEY (term, bookmark_id)
+);
+CREATE INDEX IF NOT EXISTS bookmark_terms_id ON bookmark_terms(bookmark_id);
+CREATE TABLE IF NOT EXISTS meta (
+	key   TEXT PRIMARY KEY,
+	value TEXT NOT NULL
+);
+`
+
+// likeEscaper quotes the LIKE wildcards in a literal prefix
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// SQLiteStore keeps bookmarks in a local SQLite file, for machines without Redis
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore opens (and creates if needed) the database at path
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create store directory: %v", err)
+	}
+
+	db, err := sql.Open("sqlite", "file:"+path+
+		"?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite store: %v", err)
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialise SQLite store: %v", err)
+	}
+
+	return &SQLiteStore{db: db}, nil
+}
+
+func (s *SQLiteStore) Put(ctx context.Context, bm models.Bookmark) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := putTx(ctx, tx, bm); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// putTx writes bm with its tags and terms inside tx. The url column holds
+// the canonical URL so variants of one page collide.
+func putTx(ctx context.Context, tx *sql.Tx, bm models.Bookmark) error {
+	data, err := json.Marshal(bm)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO bookmarks (id, url, created_at, data) VALUES (?, ?, ?, ?)
+		 ON CONFLICT(id) DO UPDATE SET url = excluded.url, created_at = excluded.created_at, data = excluded.data`,
+		bm.ID, urlnorm.Canonical(bm.URL), bm.CreatedAt, string(data)); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM bookmark_tags WHERE bookmark_id = ?`, bm.ID); err != nil {
+		return err
+	}
+	for _, tag := range bm.Tags {
+		if tag == "" {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT OR IGNORE INTO bookmark_tags (bookmark_id, tag) VALUES (?, ?)`, bm.ID, tag); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM bookmark_terms WHERE bookmark_id = ?`, bm.ID); err != nil {
+		return err
+	}
+	for _, term := range index.Terms(bm) {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT OR IGNORE INTO bookmark_terms (term, bookmark_id) VALUES (?, ?)`, term, bm.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQLiteStore) Get(ctx context.Context, id string) (models.Bookmark, error) {
+	var data string
+	err := s.db.QueryRowContext(ctx, `SELECT data FROM bookmarks WHERE id = ?`, id).Scan(&data)
+	if err == sql.ErrNoRows {
+		return models.Bookmark{}, ErrNotFound
+	}
+	if err != nil {
+		return models.Bookmark{}, err
+	}
+
+	var bm models.Bookmark
+	err = json.Unmarshal([]byte(data), &bm)
+	return bm, err
+}
+
+func (s *SQLiteStore) IDsWithPrefix(ctx context.Context, prefix string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id FROM bookmarks WHERE id LIKE ? ESCAPE '\'`, likeEscaper.Replace(prefix)+"%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (s *SQLiteStore) Delete(ctx context.Context, id string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM bookmarks WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *SQLiteStore) HasURL(ctx context.Context, url string) (bool, error) {
+	var exists bool
+	err := s.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM bookmarks WHERE url = ?)`, urlnorm.Canonical(url)).Scan(&exists)
+	return exists, err
+}
+
+func (s *SQLiteStore) Count(ctx context.Context) (int, error) {
+	var n int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM bookmarks`).Scan(&n)
+	return n, err
+}
+
+func (s *SQLiteStore) List(ctx context.Context) ([]models.Bookmark, error) {
+	return s.Query(ctx, Query{})
+}
+
+func (s *SQLiteStore) Query(ctx context.Context, q Query) ([]models.Bookmark, error) {
+	where, args := sqliteWhere(q)
+	query := `SELECT data FROM bookmarks` + where
+	if q.Reverse {
+		query += ` ORDER BY created_at DESC, id DESC`
+	} else {
+		query += ` ORDER BY created_at, id`
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	c := collector{q: q}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var bm models.Bookmark
+		if err := json.Unmarshal([]byte(data), &bm); err != nil {
+			continue
+		}
+		if c.add(bm) {
+			break
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return c.matches, nil
+}
+
+func (s *SQLiteStore) CountQuery(ctx context.Context, q Query) (int, error) {
+	if q.Match != nil {
+		q.Offset, q.Limit = 0, 0
+		matches, err := s.Query(ctx, q)
+		return len(matches), err
+	}
+
+	where, args := sqliteWhere(q)
+	var n int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM bookmarks`+where, args...).Scan(&n)
+	return n, err
+}
+
+// sqliteWhere turns the date, term and tag constraints of q into a WHERE clause
+func sqliteWhere(q Query) (string, []interface{}) {
+	query := ` WHERE 1 = 1`
+	var args []interface{}
+	if q.From != nil {
+		query += ` AND created_at >= ?`
+		args = append(args, *q.From)
+	}
+	if q.To != nil {
+		query += ` AND created_at <= ?`
+		args = append(args, *q.To)
+	}
+	for _, token := range q.Terms {
+		// Prefix match that can still use the primary key on term
+		query += ` AND id IN (SELECT bookmark_id FROM bookmark_terms WHERE term >= ? AND term < ?)`
+		args = append(args, token, token+string(utf8.MaxRune))
+	}
+	for _, tag := range q.Tags {
+		query += ` AND id IN (SELECT bookmark_id FROM bookmark_tags
+			WHERE tag = ? COLLATE NOCASE OR tag LIKE ? ESCAPE '\')`
+		args = append(args, tag, likeEscaper.Replace(tag)+"/%")
+	}
+	return query, args
+}
+
+func (s *SQLiteStore) DocFreq(ctx context.Context, tokens []string) (map[string]int, error) {
+	freq := make(map[string]int, len(tokens))
+	for _, token := range tokens {
+		var n int
+		if err := s.db.QueryRowContext(ctx,
+			`SELECT COUNT(DISTINCT bookmark_id) FROM bookmark_terms WHERE term >= ? AND term < ?`,
+			token, token+string(utf8.MaxRune)).Scan(&n); err != nil {
+			return nil, err
+		}
+		freq[token] = n
+	}
+	return freq, nil
+}
+
+func (s *SQLiteStore) Vocabulary(ctx context.Context, prefix string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT DISTINCT term FROM bookmark_terms WHERE term >= ? AND term < ? ORDER BY term`,
+		prefix, prefix+string(utf8.MaxRune))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var terms []string
+	for rows.Next() {
+		var term string
+		if err := rows.Scan(&term); err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+	}
+	return terms, rows.Err()
+}
+
+func (s *SQLiteStore) Tags(ctx context.Context) (map[string]int, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT tag, COUNT(*) FROM bookmark_tags GROUP BY tag`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var tag string
+		var count int
+		if err := rows.Scan(&tag, &count); err != nil {
+			return nil, err
+		}
+		counts[tag] = count
+	}
+	return counts, rows.Err()
+}
+
+func (s *SQLiteStore) Reindex(ctx context.Context, progress func(done, total int)) (int, error) {
+	bookmarks, err := s.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{"bookmark_terms", "bookmark_tags"} {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM `+table); err != nil {
+			return 0, err
+		}
+	}
+	for i, bm := range bookmarks {
+		for _, term := range index.Terms(bm) {
+			if _, err := tx.ExecContext(ctx,
+				`INSERT OR IGNORE INTO bookmark_terms (term, bookmark_id) VALUES (?, ?)`, term, bm.ID); err != nil {
+				return 0, err
+			}
+		}
+		for _, tag := range cleanTags(bm.Tags) {
+			if _, err := tx.ExecContext(ctx,
+				`INSERT OR IGNORE INTO bookmark_tags (bookmark_id, tag) VALUES (?, ?)`, bm.ID, tag); err != nil {
+				return 0, err
+			}
+		}
+		if progress != nil {
+			progress(i+1, len(bookmarks))
+		}
+	}
+
+	return len(bookmarks), tx.Commit()
+}
+
+// Dedupe catches bookmarks the unique url column cannot: those stored
+// before a change to URL canonicalisation made them the same page
+func (s *SQLiteStore) Dedupe(ctx context.Context, strategy MergeStrategy) (int, error) {
+	bookmarks, err := s.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	merged, dupes := mergeDuplicates(bookmarks, strategy)
+	if len(dupes) == 0 {
+		return 0, nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// The duplicates go first, as a survivor rewritten with the new
+	// canonical URL would clash with theirs
+	for _, id := range dupes {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM bookmarks WHERE id = ?`, id); err != nil {
+			return 0, err
+		}
+	}
+	for _, bm := range merged {
+		if err := putTx(ctx, tx, bm); err != nil {
+			return 0, err
+		}
+	}
+	return len(dupes), tx.Commit()
+}
+
+// Clean merges duplicates and removes orphaned term and tag rows, left by
+// databases written with foreign keys disabled; the tables are otherwise
+// updated in one transaction.
+func (s *SQLiteStore) Clean(ctx context.Context, opts CleanOptions) (CleanReport, error) {
+	var report CleanReport
+	if opts.DryRun {
+		bookmarks, err := s.List(ctx)
+		if err != nil {
+			return report, err
+		}
+		report.Duplicates = countDuplicates(bookmarks)
+	} else {
+		var err error
+		if report.Duplicates, err = s.Dedupe(ctx, opts.Strategy); err != nil {
+			return report, err
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return report, err
+	}
+	defer tx.Rollback()
+
+	for _, t := range []struct {
+		table string
+		count *int
+	}{
+		{"bookmark_terms", &report.OrphanTerms},
+		{"bookmark_tags", &report.OrphanTags},
+	} {
+		where := ` WHERE bookmark_id NOT IN (SELECT id FROM bookmarks)`
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+t.table+where).Scan(t.count); err != nil {
+			return report, err
+		}
+		if opts.DryRun || *t.count == 0 {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM `+t.table+where); err != nil {
+			return report, err
+		}
+	}
+	return report, tx.Commit()
+}
+
+func (s *SQLiteStore) Rekey(ctx context.Context, progress func(done, total int)) (int, error) {
+	bookmarks, err := s.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	bookmarks, merged, err := rekeyed(bookmarks)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Tags and terms go with the rows through ON DELETE CASCADE
+	if _, err := tx.ExecContext(ctx, `DELETE FROM bookmarks`); err != nil {
+		return 0, err
+	}
+	for i, bm := range bookmarks {
+		if err := putTx(ctx, tx, bm); err != nil {
+			return 0, err
+		}
+		if progress != nil {
+			progress(i+1, len(bookmarks))
+		}
+	}
+
+	return merged, tx.Commit()
+}
+
+func (s *SQLiteStore) Meta(ctx context.Context, key string) (string, error) {
+	var value string
+	err := s.db.QueryRowContext(ctx, `SELECT value FROM meta WHERE key = ?`, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return value, err
+}
+
+func (s *SQLiteStore) SetMeta(ctx context.Context, key, value string) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO meta (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value`,
+		key, value)
+	return err
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
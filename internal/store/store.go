@@ -0,0 +1,347 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/abhijith/bookmark-cli/internal/models"
+	redisclient "github.com/abhijith/bookmark-cli/internal/redis"
+	"github.com/abhijith/bookmark-cli/internal/urlnorm"
+	"github.com/joho/godotenv"
+)
+
+// ErrNotFound is returned when a bookmark does not exist in the store
+var ErrNotFound = errors.New("bookmark not found")
+
+// SchemaVersionMetaKey records which data layout a store holds
+const SchemaVersionMetaKey = "schema_version"
+
+// Query selects bookmarks from a store. Zero values mean "no constraint".
+type Query struct {
+	// From and To bound CreatedAt (inclusive)
+	From *int64
+	To   *int64
+	// Terms narrows the candidates through the term index: a bookmark must
+	// contain every token, each matched as a prefix of an indexed term
+	Terms []string
+	// Tags narrows the candidates through the tag index: a bookmark must
+	// carry every tag or one of its children, compared ignoring case
+	Tags  []string
+	Match func(models.Bookmark) bool
+	// Reverse returns the newest bookmarks first
+	Reverse bool
+	// Offset skips that many matches before Limit is applied
+	Offset int
+	Limit  int
+}
+
+// CleanOptions controls Store.Clean
+type CleanOptions struct {
+	// Strategy merges bookmarks sharing a canonical URL
+	Strategy MergeStrategy
+	// DryRun only counts the problems
+	DryRun bool
+}
+
+// CleanReport counts the problems Clean found and, unless it was a dry run,
+// fixed
+type CleanReport struct {
+	// Duplicates are bookmarks merged into an older one with the same canonical URL
+	Duplicates int
+	// MissingBookmarks are index entries whose bookmark no longer exists
+	MissingBookmarks int
+	// Unindexed are stored bookmarks missing from the index
+	Unindexed int
+	// StaleURLs are URL set members no bookmark has
+	StaleURLs int
+	// MissingURLs are bookmark URLs missing from the URL set
+	MissingURLs int
+	// OrphanTerms are term entries pointing at a missing bookmark or one
+	// without the term, plus terms left with no entries
+	OrphanTerms int
+	// OrphanTags are the same for the tag index
+	OrphanTags int
+}
+
+// Store is the persistence layer shared by the importers and the searcher
+type Store interface {
+	// Put inserts a bookmark or replaces the one with the same ID
+	Put(ctx context.Context, bm models.Bookmark) error
+	// Get returns the bookmark with the given ID or ErrNotFound
+	Get(ctx context.Context, id string) (models.Bookmark, error)
+	// IDsWithPrefix returns the IDs starting with prefix
+	IDsWithPrefix(ctx context.Context, prefix string) ([]string, error)
+	// Delete removes the bookmark with the given ID or returns ErrNotFound
+	Delete(ctx context.Context, id string) error
+	// HasURL reports whether a bookmark with the same canonical URL is stored
+	HasURL(ctx context.Context, url string) (bool, error)
+	// Count returns the number of entries in the index
+	Count(ctx context.Context) (int, error)
+	// List returns every bookmark, oldest first
+	List(ctx context.Context) ([]models.Bookmark, error)
+	// Query returns the bookmarks matching q ordered by CreatedAt, stopping
+	// as soon as the requested page is complete
+	Query(ctx context.Context, q Query) ([]models.Bookmark, error)
+	// CountQuery returns how many bookmarks match q, ignoring Offset and
+	// Limit. Without Match it is answered from the indexes alone.
+	CountQuery(ctx context.Context, q Query) (int, error)
+	// DocFreq returns, per token, how many bookmarks have an indexed term the
+	// token is a prefix of
+	DocFreq(ctx context.Context, tokens []string) (map[string]int, error)
+	// Vocabulary returns the indexed terms starting with prefix ("" for all)
+	Vocabulary(ctx context.Context, prefix string) ([]string, error)
+	// Tags returns every tag with the number of bookmarks carrying it
+	Tags(ctx context.Context) (map[string]int, error)
+	// Reindex rebuilds the term and tag indexes from scratch and returns how
+	// many bookmarks were indexed
+	Reindex(ctx context.Context, progress func(done, total int)) (int, error)
+	// Dedupe merges bookmarks that share a canonical URL into the oldest
+	// copy with strategy and returns how many were removed
+	Dedupe(ctx context.Context, strategy MergeStrategy) (int, error)
+	// Clean merges duplicates and repairs the indexes so they agree with the
+	// stored bookmarks, reporting what it found
+	Clean(ctx context.Context, opts CleanOptions) (CleanReport, error)
+	// Rekey moves every bookmark to the ID of its canonical URL, merging
+	// bookmarks that turn out to be the same page, and returns how many were merged
+	Rekey(ctx context.Context, progress func(done, total int)) (int, error)
+	// Meta and SetMeta read and write small bookkeeping values such as the last sync time
+	Meta(ctx context.Context, key string) (string, error)
+	SetMeta(ctx context.Context, key, value string) error
+	Close() error
+}
+
+// Open creates the store selected by the BOOKMARK_STORE environment variable
+// (redis, sqlite or memory). Redis is the default.
+func Open() (Store, error) {
+	// Load environment variables
+	godotenv.Load()
+
+	switch backend := strings.ToLower(os.Getenv("BOOKMARK_STORE")); backend {
+	case "", "redis":
+		return NewRedisStore(redisclient.NewClient()), nil
+	case "sqlite":
+		path := os.Getenv("BOOKMARK_SQLITE_PATH")
+		if path == "" {
+			path = filepath.Join(os.Getenv("HOME"), ".bookmark-cli", "bookmarks.db")
+		}
+		return NewSQLiteStore(path)
+	case "memory":
+		return NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown store backend: %s", backend)
+	}
+}
+
+// ShortIDLength is how many leading characters of an ID are shown; any
+// unique prefix of at least MinIDPrefix characters is accepted in its place
+const (
+	ShortIDLength = 8
+	MinIDPrefix   = 4
+)
+
+// NewID derives a bookmark ID from its canonical URL, so variants of one
+// page share an ID: the 64-bit FNV-1a hash as 16 hex digits
+func NewID(url string) string {
+	h := fnv.New64a()
+	h.Write([]byte(urlnorm.Canonical(url)))
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// ShortID returns the abbreviated form of id shown by the CLI
+func ShortID(id string) string {
+	if len(id) > ShortIDLength {
+		return id[:ShortIDLength]
+	}
+	return id
+}
+
+// HasTag reports whether bm carries tag or one of its children, ignoring case
+func HasTag(bm models.Bookmark, tag string) bool {
+	for _, t := range bm.Tags {
+		if TagMatches(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// TagMatches reports whether tag is filter or, since tags form a hierarchy
+// separated by "/", a tag below it: "dev" matches "dev/go"
+func TagMatches(tag, filter string) bool {
+	if len(tag) > len(filter) && tag[len(filter)] == '/' {
+		tag = tag[:len(filter)]
+	}
+	return strings.EqualFold(tag, filter)
+}
+
+// cleanTags returns tags without empty and repeated entries
+func cleanTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	var clean []string
+	for _, tag := range tags {
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			clean = append(clean, tag)
+		}
+	}
+	return clean
+}
+
+// rekeyed groups bookmarks by the ID of their canonical URL, merging each
+// group into its oldest bookmark with MergeUnion. It returns the bookmarks in
+// their original order and how many were merged away.
+func rekeyed(bookmarks []models.Bookmark) ([]models.Bookmark, int, error) {
+	byID := make(map[string]int, len(bookmarks))
+	var merged []models.Bookmark
+	for _, bm := range bookmarks {
+		bm.ID = NewID(bm.URL)
+		if i, ok := byID[bm.ID]; ok {
+			if err := checkCollision(merged[i], bm); err != nil {
+				return nil, 0, err
+			}
+			merged[i], _ = Merge(merged[i], bm, MergeUnion)
+			continue
+		}
+		byID[bm.ID] = len(merged)
+		merged = append(merged, bm)
+	}
+	return merged, len(bookmarks) - len(merged), nil
+}
+
+// PlanRekey returns how many bookmarks Rekey would give a new ID and how
+// many of those it would merge into another bookmark
+func PlanRekey(bookmarks []models.Bookmark) (changed, merged int, err error) {
+	for _, bm := range bookmarks {
+		if bm.ID != NewID(bm.URL) {
+			changed++
+		}
+	}
+	_, merged, err = rekeyed(bookmarks)
+	return changed, merged, err
+}
+
+// mergeDuplicates folds every bookmark sharing a canonical URL with an
+// earlier one into it with strategy. bookmarks must be oldest first, so the
+// oldest copy survives. It returns the survivors that absorbed a duplicate
+// and the IDs of the duplicates.
+func mergeDuplicates(bookmarks []models.Bookmark, strategy MergeStrategy) ([]models.Bookmark, []string) {
+	survivors := make(map[string]int)
+	var kept []models.Bookmark
+	var dupes []string
+	absorbed := make(map[int]bool)
+	for _, bm := range bookmarks {
+		key := urlnorm.Canonical(bm.URL)
+		i, ok := survivors[key]
+		if !ok {
+			survivors[key] = len(kept)
+			kept = append(kept, bm)
+			continue
+		}
+		kept[i] = Absorb(kept[i], bm, strategy)
+		absorbed[i] = true
+		dupes = append(dupes, bm.ID)
+	}
+
+	var merged []models.Bookmark
+	for i, bm := range kept {
+		if absorbed[i] {
+			merged = append(merged, bm)
+		}
+	}
+	return merged, dupes
+}
+
+// countDuplicates returns how many bookmarks share a canonical URL with
+// another one
+func countDuplicates(bookmarks []models.Bookmark) int {
+	seen := make(map[string]bool, len(bookmarks))
+	for _, bm := range bookmarks {
+		seen[urlnorm.Canonical(bm.URL)] = true
+	}
+	return len(bookmarks) - len(seen)
+}
+
+// countTags tallies the tags of the given bookmarks
+func countTags(bookmarks []models.Bookmark) map[string]int {
+	counts := make(map[string]int)
+	for _, bm := range bookmarks {
+		for _, tag := range bm.Tags {
+			if tag == "" {
+				continue
+			}
+			counts[tag]++
+		}
+	}
+	return counts
+}
+
+// sortByCreatedAt orders bookmarks oldest first (newest first when reverse),
+// breaking ties by ID
+func sortByCreatedAt(bookmarks []models.Bookmark, reverse bool) {
+	sort.Slice(bookmarks, func(i, j int) bool {
+		a, b := bookmarks[i], bookmarks[j]
+		if reverse {
+			a, b = b, a
+		}
+		if a.CreatedAt != b.CreatedAt {
+			return a.CreatedAt < b.CreatedAt
+		}
+		return a.ID < b.ID
+	})
+}
+
+// inRange reports whether bm falls inside the CreatedAt bounds of q
+func (q Query) inRange(bm models.Bookmark) bool {
+	if q.From != nil && bm.CreatedAt < *q.From {
+		return false
+	}
+	if q.To != nil && bm.CreatedAt > *q.To {
+		return false
+	}
+	return true
+}
+
+// filter applies q to bookmarks that are already in result order
+func (q Query) filter(bookmarks []models.Bookmark) []models.Bookmark {
+	c := collector{q: q}
+	for _, bm := range bookmarks {
+		if c.add(bm) {
+			break
+		}
+	}
+	return c.matches
+}
+
+// collector applies q to bookmarks fed one at a time in result order
+type collector struct {
+	q       Query
+	skipped int
+	matches []models.Bookmark
+}
+
+// add considers bm and reports whether the requested page is complete
+func (c *collector) add(bm models.Bookmark) bool {
+	if !c.q.inRange(bm) {
+		return false
+	}
+	for _, tag := range c.q.Tags {
+		if !HasTag(bm, tag) {
+			return false
+		}
+	}
+	if c.q.Match != nil && !c.q.Match(bm) {
+		return false
+	}
+	if c.skipped < c.q.Offset {
+		c.skipped++
+		return false
+	}
+	c.matches = append(c.matches, bm)
+	return c.q.Limit > 0 && len(c.matches) >= c.q.Limit
+}
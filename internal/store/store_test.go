@@ -0,0 +1,214 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/abhijith/bookmark-cli/internal/models"
+)
+
+// Every backend must pass the same tests; Redis needs a server and is left out
+func TestMemoryStoreConformance(t *testing.T) {
+	testStore(t, func(t *testing.T) Store {
+		return NewMemoryStore()
+	})
+}
+
+func TestSQLiteStoreConformance(t *testing.T) {
+	testStore(t, func(t *testing.T) Store {
+		st, err := NewSQLiteStore(filepath.Join(t.TempDir(), "bookmarks.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { st.Close() })
+		return st
+	})
+}
+
+func testStore(t *testing.T, open func(t *testing.T) Store) {
+	t.Run("PutGet", func(t *testing.T) { testPutGet(t, open(t)) })
+	t.Run("Query", func(t *testing.T) { testQuery(t, open(t)) })
+	t.Run("Delete", func(t *testing.T) { testDelete(t, open(t)) })
+}
+
+// fill stores five bookmarks created at 1 to 5; the odd ones are about Go
+// and tagged lang/go, the even ones about Rust
+func fill(t *testing.T, st Store) []models.Bookmark {
+	var bookmarks []models.Bookmark
+	for i := 1; i <= 5; i++ {
+		topic, tag := "rust", "lang/rust"
+		if i%2 == 1 {
+			topic, tag = "golang", "lang/go"
+		}
+		url := fmt.Sprintf("https://example.com/%s/%d", topic, i)
+		bm := models.Bookmark{
+			ID:        NewID(url),
+			URL:       url,
+			Title:     fmt.Sprintf("Post %d about %s", i, topic),
+			Tags:      []string{tag},
+			CreatedAt: int64(i),
+			UpdatedAt: int64(i),
+		}
+		if err := st.Put(context.Background(), bm); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+		bookmarks = append(bookmarks, bm)
+	}
+	return bookmarks
+}
+
+func createdAt(bookmarks []models.Bookmark) []int64 {
+	created := []int64{}
+	for _, bm := range bookmarks {
+		created = append(created, bm.CreatedAt)
+	}
+	return created
+}
+
+func testPutGet(t *testing.T, st Store) {
+	ctx := context.Background()
+	bm := models.Bookmark{
+		ID:          NewID("https://example.com/page"),
+		URL:         "https://example.com/page",
+		Title:       "Original title",
+		Description: "A page",
+		Tags:        []string{"one", "two/three"},
+		Folder:      "Bookmarks Bar/Dev",
+		Sources:     []string{"chrome"},
+		CreatedAt:   100,
+		UpdatedAt:   200,
+	}
+	if err := st.Put(ctx, bm); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	got, err := st.Get(ctx, bm.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if !reflect.DeepEqual(got, bm) {
+		t.Errorf("Get = %+v, want %+v", got, bm)
+	}
+	if _, err := st.Get(ctx, "missing"); err != ErrNotFound {
+		t.Errorf("Get(missing) error = %v, want ErrNotFound", err)
+	}
+	if ok, err := st.HasURL(ctx, "http://www.example.com/page/"); err != nil || !ok {
+		t.Errorf("HasURL(variant) = %v, %v; want true", ok, err)
+	}
+
+	// Replacing the bookmark moves its terms and tags
+	bm.Title, bm.Tags = "Renamed entry", []string{"four"}
+	if err := st.Put(ctx, bm); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if n, _ := st.Count(ctx); n != 1 {
+		t.Errorf("Count = %d after replacing, want 1", n)
+	}
+	if freq, _ := st.DocFreq(ctx, []string{"original", "renamed"}); freq["original"] != 0 || freq["renamed"] != 1 {
+		t.Errorf("DocFreq = %v, want only the new title indexed", freq)
+	}
+	if matches, _ := st.Query(ctx, Query{Terms: []string{"original"}}); len(matches) != 0 {
+		t.Errorf("old title still matches %d bookmarks", len(matches))
+	}
+	if tags, _ := st.Tags(ctx); !reflect.DeepEqual(tags, map[string]int{"four": 1}) {
+		t.Errorf("Tags = %v, want only four", tags)
+	}
+}
+
+func testQuery(t *testing.T, st Store) {
+	ctx := context.Background()
+	fill(t, st)
+	from, to := int64(2), int64(4)
+
+	tests := []struct {
+		name  string
+		q     Query
+		want  []int64
+		total int
+	}{
+		{"all", Query{}, []int64{1, 2, 3, 4, 5}, 5},
+		{"first page", Query{Limit: 2}, []int64{1, 2}, 5},
+		{"second page", Query{Offset: 2, Limit: 2}, []int64{3, 4}, 5},
+		{"last page", Query{Offset: 4, Limit: 2}, []int64{5}, 5},
+		{"past the end", Query{Offset: 5, Limit: 2}, []int64{}, 5},
+		{"newest first", Query{Reverse: true, Limit: 2}, []int64{5, 4}, 5},
+		{"newest second page", Query{Reverse: true, Offset: 2, Limit: 2}, []int64{3, 2}, 5},
+		{"date range", Query{From: &from, To: &to}, []int64{2, 3, 4}, 3},
+		{"term", Query{Terms: []string{"golang"}}, []int64{1, 3, 5}, 3},
+		{"term prefix", Query{Terms: []string{"gol"}, Offset: 1, Limit: 1}, []int64{3}, 3},
+		{"terms", Query{Terms: []string{"post", "rust"}, Reverse: true}, []int64{4, 2}, 2},
+		{"tag", Query{Tags: []string{"lang/rust"}}, []int64{2, 4}, 2},
+		{"parent tag", Query{Tags: []string{"LANG"}, Limit: 3}, []int64{1, 2, 3}, 5},
+		{"match", Query{Match: func(bm models.Bookmark) bool {
+			return bm.CreatedAt != 2
+		}, Offset: 1, Limit: 2}, []int64{3, 4}, 4},
+		{"no match", Query{Terms: []string{"python"}}, []int64{}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := st.Query(ctx, tt.q)
+			if err != nil {
+				t.Fatalf("Query: %v", err)
+			}
+			if !reflect.DeepEqual(createdAt(got), tt.want) {
+				t.Errorf("Query = %v, want %v", createdAt(got), tt.want)
+			}
+			total, err := st.CountQuery(ctx, tt.q)
+			if err != nil {
+				t.Fatalf("CountQuery: %v", err)
+			}
+			if total != tt.total {
+				t.Errorf("CountQuery = %d, want %d", total, tt.total)
+			}
+		})
+	}
+}
+
+func testDelete(t *testing.T, st Store) {
+	ctx := context.Background()
+	bookmarks := fill(t, st)
+	gone := bookmarks[1]
+
+	if err := st.Delete(ctx, gone.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := st.Delete(ctx, gone.ID); err != ErrNotFound {
+		t.Errorf("second Delete error = %v, want ErrNotFound", err)
+	}
+	if _, err := st.Get(ctx, gone.ID); err != ErrNotFound {
+		t.Errorf("Get after Delete error = %v, want ErrNotFound", err)
+	}
+	if n, _ := st.Count(ctx); n != 4 {
+		t.Errorf("Count = %d, want 4", n)
+	}
+	if ok, _ := st.HasURL(ctx, gone.URL); ok {
+		t.Error("HasURL still reports the deleted URL")
+	}
+	if ids, _ := st.IDsWithPrefix(ctx, gone.ID); len(ids) != 0 {
+		t.Errorf("IDsWithPrefix = %v, want none", ids)
+	}
+
+	// Terms and tags of the deleted bookmark go with it
+	if freq, _ := st.DocFreq(ctx, []string{"rust", "golang"}); freq["rust"] != 1 || freq["golang"] != 3 {
+		t.Errorf("DocFreq = %v, want rust 1 and golang 3", freq)
+	}
+	if matches, _ := st.Query(ctx, Query{Tags: []string{"lang/rust"}}); !reflect.DeepEqual(createdAt(matches), []int64{4}) {
+		t.Errorf("tag lang/rust matches %v, want [4]", createdAt(matches))
+	}
+	if tags, _ := st.Tags(ctx); !reflect.DeepEqual(tags, map[string]int{"lang/go": 3, "lang/rust": 1}) {
+		t.Errorf("Tags = %v", tags)
+	}
+
+	// The last bookmark with a term or tag takes it out of the vocabulary
+	if err := st.Delete(ctx, bookmarks[3].ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if terms, _ := st.Vocabulary(ctx, "rus"); len(terms) != 0 {
+		t.Errorf("Vocabulary(rus) = %v, want none", terms)
+	}
+	if tags, _ := st.Tags(ctx); !reflect.DeepEqual(tags, map[string]int{"lang/go": 3}) {
+		t.Errorf("Tags = %v, want only lang/go", tags)
+	}
+}
@@ -0,0 +1,351 @@
+package tags
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/abhijith/bookmark-cli/internal/bookmarks"
+	"github.com/abhijith/bookmark-cli/internal/models"
+	"github.com/abhijith/bookmark-cli/internal/searcher"
+	"github.com/abhijith/bookmark-cli/internal/store"
+	"github.com/schollz/progressbar/v3"
+	"github.com/urfave/cli/v2"
+)
+
+// ListCommand prints every tag with its bookmark count
+func ListCommand(st store.Store) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		counts, err := st.Tags(context.Background())
+		if err != nil {
+			return fmt.Errorf("failed to list tags: %v", err)
+		}
+		if len(counts) == 0 {
+			fmt.Println("No tags")
+			return nil
+		}
+
+		names := make([]string, 0, len(counts))
+		for name := range counts {
+			names = append(names, name)
+		}
+		byName := c.String("sort") == "name"
+		sort.Slice(names, func(i, j int) bool {
+			a, b := names[i], names[j]
+			if !byName && counts[a] != counts[b] {
+				return counts[a] > counts[b]
+			}
+			return strings.ToLower(a) < strings.ToLower(b)
+		})
+
+		width := len(fmt.Sprint(counts[names[0]]))
+		for _, name := range names {
+			if n := len(fmt.Sprint(counts[name])); n > width {
+				width = n
+			}
+		}
+		for _, name := range names {
+			fmt.Printf("%*d  %s\n", width, counts[name], name)
+		}
+		return nil
+	}
+}
+
+// RenameCommand renames a tag on every bookmark carrying it
+func RenameCommand(st store.Store) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		if c.NArg() != 2 {
+			return cli.Exit("Usage: tags rename <old> <new>", 1)
+		}
+		from, to := strings.TrimSpace(c.Args().Get(0)), strings.TrimSpace(c.Args().Get(1))
+		if from == "" || to == "" {
+			return cli.Exit("Tag names must not be empty", 1)
+		}
+
+		n, err := replace(st, []string{from}, to)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Renamed %q to %q on %d bookmarks\n", from, to, n)
+		return nil
+	}
+}
+
+// MergeCommand folds one or more tags into the last one given
+func MergeCommand(st store.Store) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		if c.NArg() < 2 {
+			return cli.Exit("Usage: tags merge <tag>... <into>", 1)
+		}
+		args := c.Args().Slice()
+		sources, into := args[:len(args)-1], strings.TrimSpace(args[len(args)-1])
+		if into == "" {
+			return cli.Exit("Tag names must not be empty", 1)
+		}
+
+		n, err := replace(st, sources, into)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Merged %s into %q on %d bookmarks\n", quote(sources), into, n)
+		return nil
+	}
+}
+
+// DeleteCommand removes tags from every bookmark; the bookmarks are kept
+func DeleteCommand(st store.Store) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		if c.NArg() < 1 {
+			return cli.Exit("Missing tag argument", 1)
+		}
+
+		n, err := replace(st, c.Args().Slice(), "")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Removed %s from %d bookmarks\n", quote(c.Args().Slice()), n)
+		return nil
+	}
+}
+
+// AddCommand tags the bookmarks given by ID or URL, or every result of --query
+func AddCommand(st store.Store) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		tag, targets, err := targets(st, c)
+		if err != nil {
+			return err
+		}
+
+		n, err := apply(st, targets, "Tagging", func(tags []string) []string {
+			for _, t := range tags {
+				if strings.EqualFold(t, tag) {
+					return tags
+				}
+			}
+			return append(tags, tag)
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Tagged %d of %d bookmarks with %q\n", n, len(targets), tag)
+		return nil
+	}
+}
+
+// RemoveCommand untags the bookmarks given by ID or URL, or every result of --query
+func RemoveCommand(st store.Store) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		tag, targets, err := targets(st, c)
+		if err != nil {
+			return err
+		}
+
+		n, err := apply(st, targets, "Untagging", func(tags []string) []string {
+			return without(tags, []string{tag})
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Removed %q from %d of %d bookmarks\n", tag, n, len(targets))
+		return nil
+	}
+}
+
+// targets reads the tag and the bookmarks an add or remove applies to
+func targets(st store.Store, c *cli.Context) (string, []models.Bookmark, error) {
+	if c.NArg() < 1 {
+		return "", nil, cli.Exit("Missing tag argument", 1)
+	}
+	tag := strings.TrimSpace(c.Args().First())
+	if tag == "" {
+		return "", nil, cli.Exit("Tag names must not be empty", 1)
+	}
+
+	refs, query, err := splitQuery(c.Args().Tail())
+	if err != nil {
+		return "", nil, cli.Exit(err.Error(), 1)
+	}
+	if query == "" {
+		query = c.String("query")
+	}
+	switch {
+	case query != "" && len(refs) > 0:
+		return "", nil, cli.Exit("Give bookmark IDs or --query, not both", 1)
+	case query != "":
+		result, err := searcher.Find(st, query)
+		if err != nil {
+			return "", nil, cli.Exit(fmt.Sprintf("Invalid query: %v", err), 1)
+		}
+		for _, note := range result.Notes {
+			fmt.Fprintln(os.Stderr, note)
+		}
+		return tag, result.Bookmarks, nil
+	case len(refs) == 0:
+		return "", nil, cli.Exit("Give bookmark IDs or --query", 1)
+	}
+
+	ctx := context.Background()
+	var found []models.Bookmark
+	for _, ref := range refs {
+		bm, err := bookmarks.Resolve(ctx, st, ref)
+		if err != nil {
+			return "", nil, cli.Exit(err.Error(), 1)
+		}
+		found = append(found, bm)
+	}
+	return tag, found, nil
+}
+
+// splitQuery pulls a --query flag out of the arguments after the tag, since
+// flags are only parsed before the first argument ("tag add go --query x")
+func splitQuery(args []string) (refs []string, query string, err error) {
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "--":
+			return append(refs, args[i+1:]...), query, nil
+		case arg == "--query" || arg == "-query":
+			if i+1 >= len(args) {
+				return nil, "", fmt.Errorf("flag needs an argument: %s", arg)
+			}
+			i++
+			query = args[i]
+		case strings.HasPrefix(arg, "--query="):
+			query = strings.TrimPrefix(arg, "--query=")
+		case strings.HasPrefix(arg, "-query="):
+			query = strings.TrimPrefix(arg, "-query=")
+		default:
+			refs = append(refs, arg)
+		}
+	}
+	return refs, query, nil
+}
+
+// replace swaps the tags from for to on every bookmark carrying one of them,
+// or drops them when to is empty, and returns how many bookmarks changed
+func replace(st store.Store, from []string, to string) (int, error) {
+	ctx := context.Background()
+	seen := make(map[string]bool)
+	var tagged []models.Bookmark
+	for _, tag := range from {
+		matches, err := st.Query(ctx, store.Query{Tags: []string{tag}})
+		if err != nil {
+			return 0, fmt.Errorf("failed to find bookmarks tagged %q: %v", tag, err)
+		}
+		for _, bm := range matches {
+			if !seen[bm.ID] {
+				seen[bm.ID] = true
+				tagged = append(tagged, bm)
+			}
+		}
+	}
+
+	return apply(st, tagged, "Retagging", func(tags []string) []string {
+		var out []string
+		for _, t := range tags {
+			switch {
+			case matchesAny(t, from):
+				if to != "" {
+					out = append(out, to)
+				}
+			case to != "" && childOf(t, from) >= 0:
+				// Children move along with a renamed parent: dev/go becomes code/go
+				out = append(out, to+t[childOf(t, from):])
+			default:
+				out = append(out, t)
+			}
+		}
+		// The new name may already be on the bookmark under another spelling
+		return unique(out)
+	})
+}
+
+// childOf returns where the part of tag below one of parents starts, or -1
+// when tag is not nested under any of them
+func childOf(tag string, parents []string) int {
+	for _, parent := range parents {
+		if len(tag) > len(parent) && store.TagMatches(tag, parent) {
+			return len(parent)
+		}
+	}
+	return -1
+}
+
+// apply rewrites the tags of each bookmark with fn and saves those that
+// changed, returning how many did
+func apply(st store.Store, targets []models.Bookmark, label string, fn func([]string) []string) (int, error) {
+	if len(targets) == 0 {
+		return 0, nil
+	}
+
+	ctx := context.Background()
+	bar := progressbar.Default(int64(len(targets)), label)
+	changed := 0
+	for _, bm := range targets {
+		tags := fn(append([]string(nil), bm.Tags...))
+		if !equal(tags, bm.Tags) {
+			bm.Tags = tags
+			bm.UpdatedAt = time.Now().Unix()
+			if err := st.Put(ctx, bm); err != nil {
+				return changed, fmt.Errorf("failed to save %s: %v", bm.ID, err)
+			}
+			changed++
+		}
+		bar.Add(1)
+	}
+	bar.Finish()
+	return changed, nil
+}
+
+func matchesAny(tag string, names []string) bool {
+	for _, name := range names {
+		if strings.EqualFold(tag, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// without returns tags minus those equal to one of names ignoring case
+func without(tags, names []string) []string {
+	var out []string
+	for _, t := range tags {
+		if !matchesAny(t, names) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// unique drops tags repeated ignoring case, keeping the first spelling
+func unique(tags []string) []string {
+	var out []string
+	for _, t := range tags {
+		if !matchesAny(t, out) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func quote(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = fmt.Sprintf("%q", name)
+	}
+	return strings.Join(quoted, ", ")
+}
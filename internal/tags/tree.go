@@ -0,0 +1,109 @@
+package tags
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/abhijith/bookmark-cli/internal/store"
+	"github.com/urfave/cli/v2"
+)
+
+// node is one level of the folder or tag hierarchy with the number of
+// bookmarks at or below it
+type node struct {
+	name     string
+	count    int
+	children map[string]*node
+}
+
+func newNode(name string) *node {
+	return &node{name: name, children: make(map[string]*node)}
+}
+
+// add counts a bookmark under every level of the /-separated path. seen
+// holds the nodes already counted for the bookmark, so one with the tags
+// dev/go and dev/rust is counted once under dev.
+func (n *node) add(path string, seen map[*node]bool) {
+	current := n
+	for _, segment := range strings.Split(path, "/") {
+		if segment = strings.TrimSpace(segment); segment == "" {
+			continue
+		}
+		// Levels differing only in case are one, like tags themselves
+		key := strings.ToLower(segment)
+		child, ok := current.children[key]
+		if !ok {
+			child = newNode(segment)
+			current.children[key] = child
+		}
+		if !seen[child] {
+			seen[child] = true
+			child.count++
+		}
+		current = child
+	}
+}
+
+// print writes the children of n as an indented tree
+func (n *node) print(prefix string) {
+	children := make([]*node, 0, len(n.children))
+	for _, child := range n.children {
+		children = append(children, child)
+	}
+	sort.Slice(children, func(i, j int) bool {
+		return strings.ToLower(children[i].name) < strings.ToLower(children[j].name)
+	})
+
+	for i, child := range children {
+		branch, indent := "├── ", "│   "
+		if i == len(children)-1 {
+			branch, indent = "└── ", "    "
+		}
+		fmt.Printf("%s%s%s (%d)\n", prefix, branch, child.name, child.count)
+		child.print(prefix + indent)
+	}
+}
+
+// TreeCommand prints the folder and tag hierarchies with bookmark counts
+func TreeCommand(st store.Store) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		bookmarks, err := st.List(context.Background())
+		if err != nil {
+			return fmt.Errorf("failed to list bookmarks: %v", err)
+		}
+
+		folders, tags := newNode(""), newNode("")
+		unfiled, untagged := 0, 0
+		for _, bm := range bookmarks {
+			if bm.Folder == "" {
+				unfiled++
+			} else {
+				folders.add(bm.Folder, make(map[*node]bool))
+			}
+
+			seen := make(map[*node]bool)
+			for _, tag := range bm.Tags {
+				tags.add(tag, seen)
+			}
+			if len(seen) == 0 {
+				untagged++
+			}
+		}
+
+		showFolders, showTags := !c.Bool("tags"), !c.Bool("folders")
+		if showFolders {
+			fmt.Printf("Folders (%d bookmarks without a folder)\n", unfiled)
+			folders.print("")
+		}
+		if showFolders && showTags {
+			fmt.Println()
+		}
+		if showTags {
+			fmt.Printf("Tags (%d bookmarks without tags)\n", untagged)
+			tags.print("")
+		}
+		return nil
+	}
+}
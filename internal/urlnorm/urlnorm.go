@@ -0,0 +1,190 @@
+package urlnorm
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/idna"
+)
+
+// FragmentPolicy decides what happens to the "#..." part of a URL
+type FragmentPolicy string
+
+const (
+	// FragmentDrop removes every fragment
+	FragmentDrop FragmentPolicy = "drop"
+	// FragmentKeep keeps every fragment
+	FragmentKeep FragmentPolicy = "keep"
+	// FragmentRoutes keeps only fragments that single-page apps route on
+	// ("#/inbox", "#!/inbox") and drops in-page anchors
+	FragmentRoutes FragmentPolicy = "routes"
+)
+
+// DefaultStripParams are query parameters that only track where a click
+// came from. A trailing "*" matches any parameter with that prefix.
+var DefaultStripParams = []string{
+	"utm_*", "fbclid", "gclid", "dclid", "gbraid", "wbraid", "msclkid", "yclid",
+	"mc_cid", "mc_eid", "igshid", "_ga", "_gl", "_hsenc", "_hsmi", "mkt_tok", "ref_src",
+}
+
+// Normalizer computes canonical URLs
+type Normalizer struct {
+	// StripParams lists the query parameters to drop, matched ignoring case
+	StripParams []string
+	Fragment    FragmentPolicy
+}
+
+var (
+	defaultOnce       sync.Once
+	defaultNormalizer *Normalizer
+)
+
+// Default returns the normalizer configured by BOOKMARK_URL_STRIP_PARAMS (a
+// comma-separated list replacing DefaultStripParams) and
+// BOOKMARK_URL_FRAGMENT (drop, keep or routes; routes by default)
+func Default() *Normalizer {
+	defaultOnce.Do(func() {
+		n := &Normalizer{StripParams: DefaultStripParams, Fragment: FragmentRoutes}
+		if params, ok := os.LookupEnv("BOOKMARK_URL_STRIP_PARAMS"); ok {
+			n.StripParams = nil
+			for _, p := range strings.Split(params, ",") {
+				if p = strings.TrimSpace(p); p != "" {
+					n.StripParams = append(n.StripParams, p)
+				}
+			}
+		}
+		switch policy := FragmentPolicy(strings.ToLower(os.Getenv("BOOKMARK_URL_FRAGMENT"))); policy {
+		case FragmentDrop, FragmentKeep, FragmentRoutes:
+			n.Fragment = policy
+		}
+		defaultNormalizer = n
+	})
+	return defaultNormalizer
+}
+
+// Canonical returns the form of raw used to recognise duplicates with the
+// default normalizer
+func Canonical(raw string) string {
+	return Default().Canonical(raw)
+}
+
+// Canonical returns the form of raw used to recognise duplicates: http and
+// https are one scheme, the host is lowercased, punycoded and stripped of
+// "www." and its default port, trailing slashes and tracking parameters are
+// dropped, the rest of the query is sorted and the fragment follows the
+// policy. URLs that are not http(s) are only trimmed.
+func (n *Normalizer) Canonical(raw string) string {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+	default:
+		return raw
+	}
+
+	u.Host = strings.TrimPrefix(host(u), "www.")
+	u.Scheme = "https"
+	u.User = nil
+
+	if u.Path == "" || u.Path == "/" {
+		u.Path, u.RawPath = "", ""
+	} else {
+		u.Path = strings.TrimRight(u.Path, "/")
+		u.RawPath = strings.TrimRight(u.RawPath, "/")
+	}
+
+	query := u.Query()
+	for key := range query {
+		if n.strip(key) {
+			query.Del(key)
+		}
+	}
+	u.RawQuery = query.Encode()
+	u.ForceQuery = false
+
+	u.Fragment, u.RawFragment = n.fragment(u.Fragment), ""
+	return u.String()
+}
+
+// Clean tidies a URL given by the user before it is stored: a missing
+// scheme becomes https, the scheme and host are lowercased, an
+// internationalised host is punycoded and the default port removed. Unlike
+// Canonical it keeps everything that could change which page is served.
+func Clean(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL %q: %v", raw, err)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("invalid URL %q: missing host", raw)
+	}
+	u.Host = host(u)
+	return u.String(), nil
+}
+
+// host returns the lowercased ASCII host of u without the scheme's default port
+func host(u *url.URL) string {
+	hostname, port := strings.ToLower(u.Hostname()), u.Port()
+	hostname = strings.TrimSuffix(hostname, ".")
+	if ascii, err := idna.Lookup.ToASCII(hostname); err == nil {
+		hostname = ascii
+	}
+	if (port == "80" && strings.EqualFold(u.Scheme, "http")) ||
+		(port == "443" && strings.EqualFold(u.Scheme, "https")) {
+		port = ""
+	}
+
+	if port != "" {
+		return net.JoinHostPort(hostname, port)
+	}
+	if strings.Contains(hostname, ":") {
+		// IPv6 literals keep their brackets
+		return "[" + hostname + "]"
+	}
+	return hostname
+}
+
+// strip reports whether the query parameter key is tracking noise
+func (n *Normalizer) strip(key string) bool {
+	key = strings.ToLower(key)
+	for _, p := range n.StripParams {
+		p = strings.ToLower(p)
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		} else if key == p {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *Normalizer) fragment(fragment string) string {
+	switch n.Fragment {
+	case FragmentKeep:
+		return fragment
+	case FragmentDrop:
+		return ""
+	}
+	if strings.HasPrefix(fragment, "/") || strings.HasPrefix(fragment, "!") {
+		return fragment
+	}
+	return ""
+}